@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 )
 
@@ -551,3 +552,181 @@ func BenchmarkNetHTTP_LargePayload(b *testing.B) {
 		}
 	}
 }
+
+// ============================================================================
+// Parameterized Routes
+// ============================================================================
+
+const benchParamRouteCount = 500
+
+func benchParamRouter(b *testing.B) http.Handler {
+	b.Helper()
+
+	type idMeta struct {
+		ID int `path:"id"`
+	}
+
+	r := New()
+	for i := range benchParamRouteCount {
+		path := "/api/resource" + strconv.Itoa(i) + "/:id"
+		RegisterHandlerM[struct{}, idMeta, struct{}](r.EndpointGroup, GETM(func(context.Context, struct{}, idMeta) (struct{}, error) {
+			return struct{}{}, nil
+		}, path))
+	}
+
+	handler, err := r.Handler()
+	if err != nil {
+		b.Fatalf("failed to build handler: %v", err)
+	}
+	return handler
+}
+
+// Benchmark dispatch to the last of several hundred "/:id" routes.
+func BenchmarkHTTPRPC_ParamRoutes(b *testing.B) {
+	handler := benchParamRouter(b)
+	path := "/api/resource" + strconv.Itoa(benchParamRouteCount-1) + "/42"
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for range b.N {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			b.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+}
+
+// Benchmark the route matcher alone (no request/recorder overhead).
+func BenchmarkRouteTree_Match(b *testing.B) {
+	root := newRouteNode()
+	for i := range benchParamRouteCount {
+		p, err := parseRoutePattern("/api/resource" + strconv.Itoa(i) + "/:id")
+		if err != nil {
+			b.Fatalf("parse route: %v", err)
+		}
+		if _, err := root.insert(p, nil); err != nil {
+			b.Fatalf("insert route: %v", err)
+		}
+	}
+	path := "api/resource" + strconv.Itoa(benchParamRouteCount-1) + "/42"
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for range b.N {
+		params := acquirePathParams()
+		if root.lookup(path, params) == nil {
+			b.Fatalf("expected match")
+		}
+		releasePathParams(params)
+	}
+}
+
+// Benchmark a miss against several hundred "/:id" routes. Should not allocate.
+func BenchmarkRouteTree_Miss(b *testing.B) {
+	root := newRouteNode()
+	for i := range benchParamRouteCount {
+		p, err := parseRoutePattern("/api/resource" + strconv.Itoa(i) + "/:id")
+		if err != nil {
+			b.Fatalf("parse route: %v", err)
+		}
+		if _, err := root.insert(p, nil); err != nil {
+			b.Fatalf("insert route: %v", err)
+		}
+	}
+	path := "api/resource" + strconv.Itoa(benchParamRouteCount-1) + "/42/missing"
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for range b.N {
+		params := acquirePathParams()
+		if root.lookup(path, params) != nil {
+			b.Fatalf("expected miss")
+		}
+		releasePathParams(params)
+	}
+}
+
+// ============================================================================
+// Query and Meta Decoding
+// ============================================================================
+
+type BenchQuery struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Age      int      `json:"age"`
+	Page     int      `query:"page"`
+	PageSize int      `query:"page_size"`
+	Tags     []string `query:"tag"`
+	Active   bool     `json:"active"`
+	Ignored  string   `json:"-"`
+}
+
+type BenchMeta struct {
+	OrgID     int    `path:"org_id"`
+	UserID    int    `path:"user_id"`
+	Auth      string `header:"authorization"`
+	RequestID string `header:"x-request-id,omitempty"`
+	TraceID   string `header:"x-trace-id,omitempty"`
+}
+
+func BenchmarkDecodeQueryParams(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/api/users?name=John&email=john@example.com&age=30&page=2&page_size=50&tag=a&tag=b&active=true", http.NoBody)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := decodeQueryParams[BenchQuery](req); err != nil {
+			b.Fatalf("decode: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecodeRequestMeta(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/orgs/7/users/42", http.NoBody)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Request-ID", "abc")
+	params := &pathParams{names: []string{"org_id", "user_id"}, values: []string{"7", "42"}}
+	req = withPathParams(req, params)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for range b.N {
+		if _, err := decodeRequestMeta[BenchMeta](req, nil); err != nil {
+			b.Fatalf("decode: %v", err)
+		}
+	}
+}
+
+func BenchmarkHTTPRPC_GET_QueryAndMeta(b *testing.B) {
+	r := New()
+	RegisterHandlerM(r.EndpointGroup, GETM(func(_ context.Context, q BenchQuery, m BenchMeta) (BenchRes, error) {
+		return BenchRes{Message: q.Name, UserID: m.UserID, Success: true}, nil
+	}, "/orgs/:org_id/users/:user_id"))
+
+	handler, err := r.Handler()
+	if err != nil {
+		b.Fatalf("failed to build handler: %v", err)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for range b.N {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/orgs/7/users/42?name=John&email=john@example.com&age=30&page=2&page_size=50&tag=a&tag=b&active=true", http.NoBody)
+		req.Header.Set("Authorization", "Bearer token")
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			b.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+}
@@ -0,0 +1,1004 @@
+package httprpc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// binaryFormat is a self-describing binary encoding (MessagePack, CBOR) that
+// values are mapped onto with the same rules as encoding/json: json tag
+// names, omitempty/omitzero, "-", promoted embedded fields, and
+// json/text marshalers.
+type binaryFormat struct {
+	name      string
+	newWriter func() binaryWriter
+	newReader func(data []byte) binaryReader
+}
+
+// binaryWriter appends values to an encoded buffer.
+type binaryWriter interface {
+	writeNil()
+	writeBool(b bool)
+	writeInt(i int64)
+	writeUint(u uint64)
+	writeFloat32(f float32)
+	writeFloat64(f float64)
+	writeString(s string)
+	writeBytes(b []byte)
+	writeArrayHeader(n int)
+	writeMapHeader(n int)
+	bytes() []byte
+}
+
+type binaryKind uint8
+
+const (
+	binNil binaryKind = iota
+	binBool
+	binInt
+	binUint
+	binFloat
+	binString
+	binBytes
+	binArray
+	binMap
+	binExt
+	binBreak
+)
+
+// binaryToken is one item read from the input. Arrays and maps are followed
+// by their elements; n is -1 for indefinite lengths, which end with binBreak.
+type binaryToken struct {
+	kind binaryKind
+	b    bool
+	i    int64
+	u    uint64
+	f    float64
+	s    []byte // string, bytes or extension contents
+	n    int    // array or map length
+	ext  int8   // extension type
+}
+
+// binaryReader reads tokens from an encoded buffer.
+type binaryReader interface {
+	next() (binaryToken, error)
+	remaining() int
+}
+
+func (f binaryFormat) marshal(v any) ([]byte, error) {
+	w := f.newWriter()
+	e := binaryEncoder{w: w}
+	if err := e.encode(reflect.ValueOf(v), 0); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.name, err)
+	}
+	return w.bytes(), nil
+}
+
+// unmarshal decodes a single value from data into v, which must be a non-nil
+// pointer. Trailing data is an error.
+func (f binaryFormat) unmarshal(data []byte, v any, maxDepth int) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%s: unmarshal needs a non-nil pointer, got %T", f.name, v)
+	}
+	d := binaryDecoder{r: f.newReader(data), maxDepth: maxDepth}
+	if err := d.value(rv.Elem(), 0); err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	if n := d.r.remaining(); n > 0 {
+		return fmt.Errorf("%s: unexpected %d bytes after value", f.name, n)
+	}
+	return nil
+}
+
+// decodeBinaryBody decodes r's body like DefaultCodec.DecodeBody, in format f.
+func decodeBinaryBody[Req any](r *http.Request, f binaryFormat, maxBytes int64, maxDepth int) (Req, error) {
+	var req Req
+	plan := bodyPlanFor(reflect.TypeFor[Req]())
+	if plan.err != nil {
+		return req, fmt.Errorf("decode request: %w", plan.err)
+	}
+	rv := reflect.ValueOf(&req).Elem()
+	plan.applyDefaults(rv)
+	if r.Body == nil {
+		return req, nil
+	}
+	data, err := readLimitedBody(r, maxBytes)
+	if err != nil {
+		return req, err
+	}
+	if len(data) == 0 {
+		return req, nil
+	}
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxJSONDepth
+	}
+	if err := f.unmarshal(data, &req, maxDepth); err != nil {
+		return req, fmt.Errorf("decode request: %w", err)
+	}
+	if err := plan.checkEnums(rv); err != nil {
+		return req, fmt.Errorf("decode request: %w", err)
+	}
+	return req, nil
+}
+
+// writeBinary encodes v in format f and writes it with the given status
+// (200 if zero). A Response is unwrapped as in DefaultCodec.
+func writeBinary(w http.ResponseWriter, f binaryFormat, contentType string, status int, v any) error {
+	v, status = unwrapResponse(w, v, status)
+	if !bodyAllowed(status) {
+		w.WriteHeader(status)
+		return nil
+	}
+	data, err := f.marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+	return nil
+}
+
+// maxBinaryEncodeDepth guards against cyclic values when encoding.
+const maxBinaryEncodeDepth = 1000
+
+type binaryEncoder struct {
+	w binaryWriter
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonNumberType    = reflect.TypeFor[json.Number]()
+)
+
+func (e *binaryEncoder) encode(v reflect.Value, depth int) error {
+	if depth > maxBinaryEncodeDepth {
+		return fmt.Errorf("value nested deeper than %d levels (cyclic?)", maxBinaryEncodeDepth)
+	}
+	if !v.IsValid() {
+		e.w.writeNil()
+		return nil
+	}
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		e.w.writeNil()
+		return nil
+	}
+	if done, err := e.marshaler(v, depth); done {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		e.w.writeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.w.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.w.writeUint(v.Uint())
+	case reflect.Float32:
+		e.w.writeFloat32(float32(v.Float()))
+	case reflect.Float64:
+		e.w.writeFloat64(v.Float())
+	case reflect.String:
+		if v.Type() == jsonNumberType {
+			return e.number(v.String())
+		}
+		e.w.writeString(v.String())
+	case reflect.Pointer, reflect.Interface:
+		return e.encode(v.Elem(), depth+1)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.w.writeNil()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.w.writeBytes(v.Bytes())
+			return nil
+		}
+		return e.array(v, depth)
+	case reflect.Array:
+		return e.array(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			e.w.writeNil()
+			return nil
+		}
+		return e.mapValue(v, depth)
+	case reflect.Struct:
+		return e.structValue(v, depth)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// marshaler encodes v with its TextMarshaler or json.Marshaler. Types with
+// both (time.Time, netip.Addr, ...) use MarshalText, which yields the same
+// string without a JSON round trip.
+func (e *binaryEncoder) marshaler(v reflect.Value, depth int) (bool, error) {
+	t := v.Type()
+	if t.Kind() != reflect.Pointer && !t.Implements(textMarshalerType) && !t.Implements(jsonMarshalerType) && v.CanAddr() {
+		v = v.Addr()
+		t = v.Type()
+	}
+	if !v.CanInterface() {
+		return false, nil
+	}
+	if t.Implements(textMarshalerType) {
+		m, _ := v.Interface().(encoding.TextMarshaler)
+		text, err := m.MarshalText()
+		if err != nil {
+			return true, fmt.Errorf("marshal %s: %w", t, err)
+		}
+		e.w.writeString(string(text))
+		return true, nil
+	}
+	if t.Implements(jsonMarshalerType) {
+		m, _ := v.Interface().(json.Marshaler)
+		data, err := m.MarshalJSON()
+		if err != nil {
+			return true, fmt.Errorf("marshal %s: %w", t, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var generic any
+		if err := dec.Decode(&generic); err != nil {
+			return true, fmt.Errorf("marshal %s: %w", t, err)
+		}
+		return true, e.encode(reflect.ValueOf(generic), depth+1)
+	}
+	return false, nil
+}
+
+func (e *binaryEncoder) number(s string) error {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		e.w.writeInt(i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		e.w.writeUint(u)
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	e.w.writeFloat64(f)
+	return nil
+}
+
+func (e *binaryEncoder) array(v reflect.Value, depth int) error {
+	e.w.writeArrayHeader(v.Len())
+	for i := range v.Len() {
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapValue encodes a map with its keys converted to strings and sorted, as
+// encoding/json does.
+func (e *binaryEncoder) mapValue(v reflect.Value, depth int) error {
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKeyString(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, val: iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.key, b.key) })
+
+	e.w.writeMapHeader(len(entries))
+	for _, en := range entries {
+		e.w.writeString(en.key)
+		if err := e.encode(en.val, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("marshal map key %s: %w", k.Type(), err)
+		}
+		return string(text), nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported map key type %s", k.Type())
+	}
+}
+
+func (e *binaryEncoder) structValue(v reflect.Value, depth int) error {
+	fields := binaryFieldsOf(v.Type()).fields
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		fv, ok := fieldByIndexNoAlloc(v, f.index)
+		if !ok || f.omit(fv) {
+			continue
+		}
+		values = append(values, fv)
+		names = append(names, f.name)
+	}
+	e.w.writeMapHeader(len(values))
+	for i, fv := range values {
+		e.w.writeString(names[i])
+		if err := e.encode(fv, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndexNoAlloc returns the field at index, or false if it sits behind
+// a nil embedded pointer.
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// binaryField is a struct field as encoding/json sees it.
+type binaryField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	omitZero  bool
+	tagged    bool
+}
+
+func (f binaryField) omit(v reflect.Value) bool {
+	if f.omitZero && isZeroForJSON(v) {
+		return true
+	}
+	if !f.omitEmpty {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// isZeroForJSON reports whether omitzero drops v: its IsZero method if it
+// has one, reflect's zero check otherwise.
+func isZeroForJSON(v reflect.Value) bool {
+	if v.CanInterface() {
+		if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+			if v.Kind() == reflect.Pointer && v.IsNil() {
+				return true
+			}
+			return z.IsZero()
+		}
+	}
+	return v.IsZero()
+}
+
+type binaryStruct struct {
+	fields []binaryField
+	byName map[string]int
+}
+
+// lookup matches key like encoding/json: exactly, then case-insensitively.
+func (s *binaryStruct) lookup(key string) (binaryField, bool) {
+	if i, ok := s.byName[key]; ok {
+		return s.fields[i], true
+	}
+	for _, f := range s.fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return binaryField{}, false
+}
+
+var binaryStructs sync.Map // reflect.Type -> *binaryStruct
+
+func binaryFieldsOf(t reflect.Type) *binaryStruct {
+	if s, ok := binaryStructs.Load(t); ok {
+		out, _ := s.(*binaryStruct)
+		return out
+	}
+	type candidate struct {
+		binaryField
+		depth int
+	}
+	byName := map[string][]candidate{}
+	var order []string
+	var collect func(t reflect.Type, index []int, depth int, visiting []reflect.Type)
+	collect = func(t reflect.Type, index []int, depth int, visiting []reflect.Type) {
+		visiting = append(visiting, t)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			fieldIndex := append(append([]int(nil), index...), i)
+			if isPromotedJSONEmbed(f) {
+				if et := deref(f.Type); !slices.Contains(visiting, et) {
+					collect(et, fieldIndex, depth+1, visiting)
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			tag, hasTag := f.Tag.Lookup("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = f.Name
+			}
+			optList := strings.Split(opts, ",")
+			if _, ok := byName[name]; !ok {
+				order = append(order, name)
+			}
+			byName[name] = append(byName[name], candidate{
+				binaryField: binaryField{
+					name:      name,
+					index:     fieldIndex,
+					omitEmpty: slices.Contains(optList, "omitempty"),
+					omitZero:  slices.Contains(optList, "omitzero"),
+					tagged:    hasTag && tag != "" && !strings.HasPrefix(tag, ","),
+				},
+				depth: depth,
+			})
+		}
+	}
+	collect(t, nil, 0, nil)
+
+	// Keep the dominant field per name: the shallowest, preferring a tagged
+	// one; ambiguous names are dropped.
+	s := &binaryStruct{byName: map[string]int{}}
+	for _, name := range order {
+		cands := byName[name]
+		minDepth := slices.MinFunc(cands, func(a, b candidate) int { return a.depth - b.depth }).depth
+		var best []candidate
+		for _, c := range cands {
+			if c.depth == minDepth {
+				best = append(best, c)
+			}
+		}
+		if len(best) > 1 {
+			tagged := slices.DeleteFunc(best, func(c candidate) bool { return !c.tagged })
+			if len(tagged) != 1 {
+				continue
+			}
+			best = tagged
+		}
+		s.fields = append(s.fields, best[0].binaryField)
+	}
+	slices.SortFunc(s.fields, func(a, b binaryField) int { return slices.Compare(a.index, b.index) })
+	for i, f := range s.fields {
+		s.byName[f.name] = i
+	}
+	out, _ := binaryStructs.LoadOrStore(t, s)
+	cached, _ := out.(*binaryStruct)
+	return cached
+}
+
+type binaryDecoder struct {
+	r        binaryReader
+	maxDepth int
+}
+
+// binaryPathError reports the JSON pointer of the value that failed to decode.
+type binaryPathError struct {
+	path []string
+	err  error
+}
+
+func (e *binaryPathError) Error() string {
+	parts := make([]string, len(e.path))
+	for i, p := range e.path {
+		parts[i] = escapeJSONPointer(p)
+	}
+	ptr := ""
+	if len(parts) > 0 {
+		ptr = "/" + strings.Join(parts, "/")
+	}
+	return pointerOrRoot(ptr) + ": " + e.err.Error()
+}
+
+func (e *binaryPathError) Unwrap() error { return e.err }
+
+func atPath(err error, key string) error {
+	var pe *binaryPathError
+	if errors.As(err, &pe) {
+		pe.path = append([]string{key}, pe.path...)
+		return pe
+	}
+	return &binaryPathError{path: []string{key}, err: err}
+}
+
+func (d *binaryDecoder) value(v reflect.Value, depth int) error {
+	tok, err := d.r.next()
+	if err != nil {
+		return err //nolint:wrapcheck // reader errors carry their offset
+	}
+	return d.decode(tok, v, depth)
+}
+
+func (d *binaryDecoder) decode(tok binaryToken, v reflect.Value, depth int) error {
+	switch tok.kind {
+	case binBreak:
+		return errors.New("unexpected break")
+	case binNil:
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			v.SetZero()
+		default: // like encoding/json, null leaves other values unchanged
+		}
+		return nil
+	case binArray, binMap:
+		if depth+1 > d.maxDepth {
+			return fmt.Errorf("exceeds max depth %d", d.maxDepth)
+		}
+	default:
+	}
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if done, err := d.unmarshaler(tok, v, depth); done {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("cannot decode %s into %s", tok.kind, v.Type())
+		}
+		g, err := d.generic(tok, depth)
+		if err != nil {
+			return err
+		}
+		if g == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(g))
+		}
+		return nil
+	case reflect.Struct:
+		if tok.kind != binMap {
+			return fmt.Errorf("cannot decode %s into %s", tok.kind, v.Type())
+		}
+		return d.structValue(tok.n, v, depth)
+	case reflect.Map:
+		if tok.kind != binMap {
+			return fmt.Errorf("cannot decode %s into %s", tok.kind, v.Type())
+		}
+		return d.mapValue(tok.n, v, depth)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && (tok.kind == binBytes || tok.kind == binString) {
+			v.SetBytes(bytes.Clone(tok.s))
+			return nil
+		}
+		if tok.kind != binArray {
+			return fmt.Errorf("cannot decode %s into %s", tok.kind, v.Type())
+		}
+		return d.sliceValue(tok.n, v, depth)
+	case reflect.Array:
+		if tok.kind != binArray {
+			return fmt.Errorf("cannot decode %s into %s", tok.kind, v.Type())
+		}
+		return d.arrayValue(tok.n, v, depth)
+	default:
+		return setBinaryScalar(tok, v)
+	}
+}
+
+// unmarshaler decodes tok with v's TextUnmarshaler (strings only) or
+// json.Unmarshaler.
+func (d *binaryDecoder) unmarshaler(tok binaryToken, v reflect.Value, depth int) (bool, error) {
+	if !v.CanAddr() || !v.Addr().CanInterface() {
+		return false, nil
+	}
+	pv := v.Addr().Interface()
+	if u, ok := pv.(encoding.TextUnmarshaler); ok && (tok.kind == binString || tok.kind == binBytes) {
+		if err := u.UnmarshalText(tok.s); err != nil {
+			return true, fmt.Errorf("cannot decode into %s: %w", v.Type(), err)
+		}
+		return true, nil
+	}
+	if u, ok := pv.(json.Unmarshaler); ok {
+		g, err := d.generic(tok, depth)
+		if err != nil {
+			return true, err
+		}
+		data, err := json.Marshal(g)
+		if err != nil {
+			return true, fmt.Errorf("cannot decode into %s: %w", v.Type(), err)
+		}
+		if err := u.UnmarshalJSON(data); err != nil {
+			return true, fmt.Errorf("cannot decode into %s: %w", v.Type(), err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// elements reads up to n tokens (until break if n < 0) and passes each to fn.
+func (d *binaryDecoder) elements(n int, fn func(i int, tok binaryToken) error) error {
+	for i := 0; n < 0 || i < n; i++ {
+		tok, err := d.r.next()
+		if err != nil {
+			return err //nolint:wrapcheck // reader errors carry their offset
+		}
+		if tok.kind == binBreak {
+			if n < 0 {
+				return nil
+			}
+			return errors.New("unexpected break")
+		}
+		if err := fn(i, tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *binaryDecoder) structValue(n int, v reflect.Value, depth int) error {
+	fields := binaryFieldsOf(v.Type())
+	return d.elements(n, func(_ int, keyTok binaryToken) error {
+		if keyTok.kind != binString && keyTok.kind != binBytes {
+			return fmt.Errorf("cannot use %s as a field name of %s", keyTok.kind, v.Type())
+		}
+		key := string(keyTok.s)
+		f, ok := fields.lookup(key)
+		if !ok {
+			return d.skipValue(depth + 1)
+		}
+		if err := d.value(fieldByIndexAlloc(v, f.index), depth+1); err != nil {
+			return atPath(err, key)
+		}
+		return nil
+	})
+}
+
+func (d *binaryDecoder) mapValue(n int, v reflect.Value, depth int) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	return d.elements(n, func(_ int, keyTok binaryToken) error {
+		key := reflect.New(t.Key()).Elem()
+		if err := setBinaryMapKey(keyTok, key); err != nil {
+			return err
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := d.value(elem, depth+1); err != nil {
+			return atPath(err, fmt.Sprint(key.Interface()))
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	})
+}
+
+func (d *binaryDecoder) sliceValue(n int, v reflect.Value, depth int) error {
+	capacity := max(n, 0)
+	out := reflect.MakeSlice(v.Type(), 0, capacity)
+	err := d.elements(n, func(i int, tok binaryToken) error {
+		out = reflect.Append(out, reflect.New(v.Type().Elem()).Elem())
+		if err := d.decode(tok, out.Index(i), depth+1); err != nil {
+			return atPath(err, strconv.Itoa(i))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	v.Set(out)
+	return nil
+}
+
+func (d *binaryDecoder) arrayValue(n int, v reflect.Value, depth int) error {
+	v.SetZero()
+	return d.elements(n, func(i int, tok binaryToken) error {
+		if i >= v.Len() {
+			return d.skip(tok, depth+1)
+		}
+		if err := d.decode(tok, v.Index(i), depth+1); err != nil {
+			return atPath(err, strconv.Itoa(i))
+		}
+		return nil
+	})
+}
+
+func (d *binaryDecoder) skipValue(depth int) error {
+	tok, err := d.r.next()
+	if err != nil {
+		return err //nolint:wrapcheck // reader errors carry their offset
+	}
+	return d.skip(tok, depth)
+}
+
+func (d *binaryDecoder) skip(tok binaryToken, depth int) error {
+	switch tok.kind {
+	case binArray, binMap:
+		if depth+1 > d.maxDepth {
+			return fmt.Errorf("exceeds max depth %d", d.maxDepth)
+		}
+		n := tok.n
+		if tok.kind == binMap && n > 0 {
+			n *= 2
+		}
+		return d.elements(n, func(_ int, tok binaryToken) error { return d.skip(tok, depth+1) })
+	case binBreak:
+		return errors.New("unexpected break")
+	default:
+		return nil
+	}
+}
+
+// generic decodes tok into the natural Go value: nil, bool, int64 (uint64
+// beyond its range), float64, string, []byte, []any or map[string]any.
+func (d *binaryDecoder) generic(tok binaryToken, depth int) (any, error) {
+	switch tok.kind {
+	case binNil:
+		return nil, nil //nolint:nilnil // null decodes to a nil value
+	case binBool:
+		return tok.b, nil
+	case binInt:
+		return tok.i, nil
+	case binUint:
+		if tok.u <= math.MaxInt64 {
+			return int64(tok.u), nil
+		}
+		return tok.u, nil
+	case binFloat:
+		return tok.f, nil
+	case binString:
+		return string(tok.s), nil
+	case binBytes:
+		return bytes.Clone(tok.s), nil
+	case binArray:
+		if depth+1 > d.maxDepth {
+			return nil, fmt.Errorf("exceeds max depth %d", d.maxDepth)
+		}
+		out := make([]any, 0, max(tok.n, 0))
+		err := d.elements(tok.n, func(i int, tok binaryToken) error {
+			g, err := d.generic(tok, depth+1)
+			if err != nil {
+				return atPath(err, strconv.Itoa(i))
+			}
+			out = append(out, g)
+			return nil
+		})
+		return out, err
+	case binMap:
+		if depth+1 > d.maxDepth {
+			return nil, fmt.Errorf("exceeds max depth %d", d.maxDepth)
+		}
+		out := make(map[string]any, max(tok.n, 0))
+		err := d.elements(tok.n, func(_ int, keyTok binaryToken) error {
+			k, err := d.generic(keyTok, depth+1)
+			if err != nil {
+				return err
+			}
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			valTok, err := d.r.next()
+			if err != nil {
+				return err //nolint:wrapcheck // reader errors carry their offset
+			}
+			if out[key], err = d.generic(valTok, depth+1); err != nil {
+				return atPath(err, key)
+			}
+			return nil
+		})
+		return out, err
+	case binExt:
+		return nil, fmt.Errorf("unsupported extension type %d", tok.ext)
+	case binBreak:
+		return nil, errors.New("unexpected break")
+	default:
+		return nil, fmt.Errorf("unsupported %s", tok.kind)
+	}
+}
+
+func setBinaryScalar(tok binaryToken, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if tok.kind == binBool {
+			v.SetBool(tok.b)
+			return nil
+		}
+	case reflect.String:
+		if tok.kind == binString || tok.kind == binBytes {
+			v.SetString(string(tok.s))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := tokenInt(tok); ok {
+			if v.OverflowInt(i) {
+				return fmt.Errorf("%d overflows %s", i, v.Type())
+			}
+			v.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u, ok := tokenUint(tok); ok {
+			if v.OverflowUint(u) {
+				return fmt.Errorf("%d overflows %s", u, v.Type())
+			}
+			v.SetUint(u)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch tok.kind {
+		case binFloat:
+			f = tok.f
+		case binInt:
+			f = float64(tok.i)
+		case binUint:
+			f = float64(tok.u)
+		default:
+			return fmt.Errorf("cannot decode %s into %s", tok.kind, v.Type())
+		}
+		if v.OverflowFloat(f) {
+			return fmt.Errorf("%g overflows %s", f, v.Type())
+		}
+		v.SetFloat(f)
+		return nil
+	default:
+	}
+	return fmt.Errorf("cannot decode %s into %s", tok.kind, v.Type())
+}
+
+// tokenInt returns tok as an int64. Floats are accepted if they are whole
+// numbers, since some encoders don't distinguish them.
+func tokenInt(tok binaryToken) (int64, bool) {
+	switch tok.kind {
+	case binInt:
+		return tok.i, true
+	case binUint:
+		return int64(tok.u), tok.u <= math.MaxInt64
+	case binFloat:
+		if tok.f == math.Trunc(tok.f) && tok.f >= math.MinInt64 && tok.f < math.MaxInt64 {
+			return int64(tok.f), true
+		}
+	default:
+	}
+	return 0, false
+}
+
+func tokenUint(tok binaryToken) (uint64, bool) {
+	switch tok.kind {
+	case binUint:
+		return tok.u, true
+	case binInt:
+		return uint64(tok.i), tok.i >= 0
+	case binFloat:
+		if tok.f == math.Trunc(tok.f) && tok.f >= 0 && tok.f < math.MaxUint64 {
+			return uint64(tok.f), true
+		}
+	default:
+	}
+	return 0, false
+}
+
+func setBinaryMapKey(tok binaryToken, key reflect.Value) error {
+	if key.Kind() == reflect.String && (tok.kind == binString || tok.kind == binBytes) {
+		key.SetString(string(tok.s))
+		return nil
+	}
+	if u, ok := key.Addr().Interface().(encoding.TextUnmarshaler); ok && (tok.kind == binString || tok.kind == binBytes) {
+		if err := u.UnmarshalText(tok.s); err != nil {
+			return fmt.Errorf("cannot decode map key into %s: %w", key.Type(), err)
+		}
+		return nil
+	}
+	if tok.kind == binString || tok.kind == binBytes {
+		// encoding/json writes integer keys as strings.
+		switch key.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(string(tok.s), 10, 64)
+			if err != nil || key.OverflowInt(i) {
+				return fmt.Errorf("cannot decode map key %q into %s", tok.s, key.Type())
+			}
+			key.SetInt(i)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u, err := strconv.ParseUint(string(tok.s), 10, 64)
+			if err != nil || key.OverflowUint(u) {
+				return fmt.Errorf("cannot decode map key %q into %s", tok.s, key.Type())
+			}
+			key.SetUint(u)
+			return nil
+		default:
+		}
+	}
+	return setBinaryScalar(tok, key)
+}
+
+func (k binaryKind) String() string {
+	switch k {
+	case binNil:
+		return "nil"
+	case binBool:
+		return "bool"
+	case binInt, binUint:
+		return "integer"
+	case binFloat:
+		return "float"
+	case binString:
+		return "string"
+	case binBytes:
+		return "bytes"
+	case binArray:
+		return "array"
+	case binMap:
+		return "map"
+	case binExt:
+		return "extension"
+	case binBreak:
+		return "break"
+	default:
+		return "unknown"
+	}
+}
+
+// lengthFits reports whether a container of n items, each at least one byte
+// per unit, can fit in the remaining input. It stops corrupt length headers
+// from forcing large allocations.
+func lengthFits(n, units uint64, remaining int) bool {
+	return n <= uint64(remaining)/units
+}
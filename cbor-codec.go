@@ -0,0 +1,346 @@
+//nolint:mnd,gosec // byte values and integer widths are fixed by the wire format
+package httprpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+)
+
+// CBORCodec encodes bodies as CBOR (RFC 8949). Fields are named by their json
+// tags and follow the encoding/json rules, so the generated TypeScript types
+// describe both encodings. Output uses the preferred serialization: shortest
+// integer heads and the smallest float width that keeps the value. Decoding
+// accepts indefinite lengths and ignores tags. GET requests decode the query
+// string as in DefaultCodec.
+type CBORCodec[Req any, Res any] struct {
+	DefaultCodec[Req, Res]
+
+	// MaxBytes limits the request body size. Zero means DefaultMaxBodyBytes.
+	MaxBytes int64
+	// MaxDepth limits how deeply arrays and maps may nest. Zero means DefaultMaxJSONDepth.
+	MaxDepth int
+}
+
+// Consumes returns the content types this codec can decode.
+func (c CBORCodec[Req, Res]) Consumes() []string { return []string{"application/cbor"} }
+
+// Produces returns the content types this codec can encode.
+func (c CBORCodec[Req, Res]) Produces() []string { return []string{"application/cbor"} }
+
+// DecodeBody decodes a CBOR body into the request type.
+func (c CBORCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
+	return decodeBinaryBody[Req](r, cborFormat, c.MaxBytes, c.MaxDepth)
+}
+
+// Encode encodes the response as CBOR.
+func (c CBORCodec[Req, Res]) Encode(w http.ResponseWriter, res Res) error {
+	return writeBinary(w, cborFormat, "application/cbor", c.Status, res)
+}
+
+// EncodeError encodes an error as CBOR with the same problem members and status
+// as DefaultCodec.
+func (c CBORCodec[Req, Res]) EncodeError(w http.ResponseWriter, err error) error {
+	p := problemFor(err)
+	return writeBinary(w, cborFormat, "application/cbor", p.Status, p)
+}
+
+var cborFormat = binaryFormat{
+	name:      "cbor",
+	newWriter: func() binaryWriter { return &cborWriter{} },
+	newReader: func(data []byte) binaryReader { return &cborReader{data: data} },
+}
+
+const (
+	cborUint byte = iota << 5
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const cborBreakByte = 0xff
+
+type cborWriter struct {
+	buf []byte
+}
+
+func (w *cborWriter) bytes() []byte { return w.buf }
+
+// head writes a major type with its argument in the shortest form.
+func (w *cborWriter) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		w.buf = append(w.buf, major|byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, major|26), uint32(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, major|27), n)
+	}
+}
+
+func (w *cborWriter) writeNil() { w.buf = append(w.buf, cborSimple|22) }
+
+func (w *cborWriter) writeBool(b bool) {
+	if b {
+		w.buf = append(w.buf, cborSimple|21)
+		return
+	}
+	w.buf = append(w.buf, cborSimple|20)
+}
+
+func (w *cborWriter) writeInt(i int64) {
+	if i >= 0 {
+		w.head(cborUint, uint64(i))
+		return
+	}
+	w.head(cborNegInt, uint64(-1-i))
+}
+
+func (w *cborWriter) writeUint(u uint64) { w.head(cborUint, u) }
+
+func (w *cborWriter) writeFloat32(f float32) { w.writeFloat64(float64(f)) }
+
+func (w *cborWriter) writeFloat64(f float64) {
+	if h, ok := float16Bits(f); ok {
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, cborSimple|25), h)
+		return
+	}
+	if f32 := float32(f); float64(f32) == f {
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, cborSimple|26), math.Float32bits(f32))
+		return
+	}
+	w.buf = binary.BigEndian.AppendUint64(append(w.buf, cborSimple|27), math.Float64bits(f))
+}
+
+func (w *cborWriter) writeString(s string) {
+	w.head(cborText, uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *cborWriter) writeBytes(b []byte) {
+	w.head(cborBytes, uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *cborWriter) writeArrayHeader(n int) { w.head(cborArray, uint64(n)) }
+
+func (w *cborWriter) writeMapHeader(n int) { w.head(cborMap, uint64(n)) }
+
+// float16Bits returns f as an IEEE 754 half-precision value if that is exact.
+// NaNs become the canonical quiet NaN.
+func float16Bits(f float64) (uint16, bool) {
+	if math.IsNaN(f) {
+		return 0x7e00, true
+	}
+	f32 := float32(f)
+	if float64(f32) != f {
+		return 0, false
+	}
+	bits := math.Float32bits(f32)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits >> 23 & 0xff)
+	mant := bits & 0x7fffff
+	switch {
+	case exp == 0xff: // infinity
+		return sign | 0x7c00, true
+	case exp == 0 && mant == 0:
+		return sign, true
+	case exp == 0: // float32 subnormals are far below the half range
+		return 0, false
+	}
+	e := exp - 127
+	switch {
+	case e >= -14 && e <= 15: // normal half
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(e+15)<<10 | uint16(mant>>13), true
+	case e >= -24 && e < -14: // subnormal half: value = m * 2^-24
+		full := mant | 0x800000
+		shift := uint(-(e + 1))
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(full>>shift), true
+	default:
+		return 0, false
+	}
+}
+
+func float16ToFloat64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h >> 10 & 0x1f)
+	mant := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(mant+0x400, exp-25)
+	}
+}
+
+type cborReader struct {
+	data []byte
+	off  int
+}
+
+func (r *cborReader) remaining() int { return len(r.data) - r.off }
+
+func (r *cborReader) take(n uint64) ([]byte, error) {
+	if n > uint64(r.remaining()) {
+		return nil, fmt.Errorf("offset %d: %w", r.off, io.ErrUnexpectedEOF)
+	}
+	b := r.data[r.off : r.off+int(n)]
+	r.off += int(n)
+	return b, nil
+}
+
+// arg reads the argument encoded by the additional information info.
+func (r *cborReader) arg(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, fmt.Errorf("offset %d: invalid additional information %d", r.off-1, info)
+	}
+	b, err := r.take(1 << (info - 24))
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (r *cborReader) next() (binaryToken, error) {
+	for {
+		start := r.off
+		b, err := r.take(1)
+		if err != nil {
+			return binaryToken{}, err
+		}
+		major, info := b[0]&0xe0, b[0]&0x1f
+
+		if major == cborSimple {
+			return r.simple(info, start)
+		}
+		if info == 31 {
+			return r.indefinite(major, start)
+		}
+		n, err := r.arg(info)
+		if err != nil {
+			return binaryToken{}, err
+		}
+		switch major {
+		case cborUint:
+			return binaryToken{kind: binUint, u: n}, nil
+		case cborNegInt:
+			if n > math.MaxInt64 {
+				return binaryToken{}, fmt.Errorf("offset %d: negative integer overflows int64", start)
+			}
+			return binaryToken{kind: binInt, i: -1 - int64(n)}, nil
+		case cborBytes, cborText:
+			s, err := r.take(n)
+			return binaryToken{kind: stringKind(major), s: s}, err
+		case cborArray, cborMap:
+			units := uint64(1)
+			if major == cborMap {
+				units = 2
+			}
+			if !lengthFits(n, units, r.remaining()) {
+				return binaryToken{}, fmt.Errorf("offset %d: %d items: %w", start, n, io.ErrUnexpectedEOF)
+			}
+			kind := binArray
+			if major == cborMap {
+				kind = binMap
+			}
+			return binaryToken{kind: kind, n: int(n)}, nil
+		case cborTag: // decode the tagged item as is
+		}
+	}
+}
+
+func stringKind(major byte) binaryKind {
+	if major == cborText {
+		return binString
+	}
+	return binBytes
+}
+
+func (r *cborReader) simple(info byte, start int) (binaryToken, error) {
+	switch info {
+	case 20, 21:
+		return binaryToken{kind: binBool, b: info == 21}, nil
+	case 22, 23: // null, undefined
+		return binaryToken{kind: binNil}, nil
+	case 25:
+		u, err := r.arg(info)
+		return binaryToken{kind: binFloat, f: float16ToFloat64(uint16(u))}, err
+	case 26:
+		u, err := r.arg(info)
+		return binaryToken{kind: binFloat, f: float64(math.Float32frombits(uint32(u)))}, err
+	case 27:
+		u, err := r.arg(info)
+		return binaryToken{kind: binFloat, f: math.Float64frombits(u)}, err
+	case 31:
+		return binaryToken{kind: binBreak}, nil
+	default:
+		return binaryToken{}, fmt.Errorf("offset %d: unsupported simple value %d", start, info)
+	}
+}
+
+// indefinite starts an indefinite-length item. Strings are read whole by
+// joining their chunks.
+func (r *cborReader) indefinite(major byte, start int) (binaryToken, error) {
+	switch major {
+	case cborArray:
+		return binaryToken{kind: binArray, n: -1}, nil
+	case cborMap:
+		return binaryToken{kind: binMap, n: -1}, nil
+	case cborBytes, cborText:
+		var s []byte
+		for {
+			b, err := r.take(1)
+			if err != nil {
+				return binaryToken{}, err
+			}
+			if b[0] == cborBreakByte {
+				return binaryToken{kind: stringKind(major), s: s}, nil
+			}
+			if b[0]&0xe0 != major || b[0]&0x1f == 31 {
+				return binaryToken{}, fmt.Errorf("offset %d: invalid chunk in indefinite-length string", r.off-1)
+			}
+			n, err := r.arg(b[0] & 0x1f)
+			if err != nil {
+				return binaryToken{}, err
+			}
+			chunk, err := r.take(n)
+			if err != nil {
+				return binaryToken{}, err
+			}
+			s = append(s, chunk...)
+		}
+	default:
+		return binaryToken{}, fmt.Errorf("offset %d: invalid indefinite length", start)
+	}
+}
@@ -0,0 +1,199 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vectors from RFC 8949 Appendix A.
+var cborVectors = []struct {
+	value any
+	hex   string
+}{
+	{uint64(0), "00"},
+	{uint64(1), "01"},
+	{uint64(10), "0a"},
+	{uint64(23), "17"},
+	{uint64(24), "1818"},
+	{uint64(25), "1819"},
+	{uint64(100), "1864"},
+	{uint64(1000), "1903e8"},
+	{uint64(1000000), "1a000f4240"},
+	{uint64(1000000000000), "1b000000e8d4a51000"},
+	{uint64(18446744073709551615), "1bffffffffffffffff"},
+	{int64(-1), "20"},
+	{int64(-10), "29"},
+	{int64(-100), "3863"},
+	{int64(-1000), "3903e7"},
+	{0.0, "f90000"},
+	{math.Copysign(0, -1), "f98000"},
+	{1.0, "f93c00"},
+	{1.1, "fb3ff199999999999a"},
+	{1.5, "f93e00"},
+	{65504.0, "f97bff"},
+	{100000.0, "fa47c35000"},
+	{3.4028234663852886e+38, "fa7f7fffff"},
+	{1.0e+300, "fb7e37e43c8800759c"},
+	{5.960464477539063e-8, "f90001"},
+	{0.00006103515625, "f90400"},
+	{-4.0, "f9c400"},
+	{-4.1, "fbc010666666666666"},
+	{math.Inf(1), "f97c00"},
+	{math.Inf(-1), "f9fc00"},
+	{false, "f4"},
+	{true, "f5"},
+	{nil, "f6"},
+	{"", "60"},
+	{"a", "6161"},
+	{"IETF", "6449455446"},
+	{"\"\\", "62225c"},
+	{"ü", "62c3bc"},
+	{"水", "63e6b0b4"},
+	{[]byte{}, "40"},
+	{[]byte{1, 2, 3, 4}, "4401020304"},
+	{[]any{}, "80"},
+	{[]any{int64(1), int64(2), int64(3)}, "83010203"},
+	{[]any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}, "8301820203820405"},
+	{map[string]any{}, "a0"},
+	{map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}, "a26161016162820203"},
+	{[]any{"a", map[string]any{"b": "c"}}, "826161a161626163"},
+}
+
+func TestCBOR_RFCVectors(t *testing.T) {
+	for _, tc := range cborVectors {
+		want, err := hex.DecodeString(tc.hex)
+		if err != nil {
+			t.Fatalf("bad vector %s: %v", tc.hex, err)
+		}
+		got, err := cborFormat.marshal(tc.value)
+		if err != nil {
+			t.Fatalf("marshal %#v: %v", tc.value, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("marshal %#v: got %x, want %s", tc.value, got, tc.hex)
+		}
+
+		var decoded any
+		if err := cborFormat.unmarshal(want, &decoded, DefaultMaxJSONDepth); err != nil {
+			t.Fatalf("unmarshal %s: %v", tc.hex, err)
+		}
+		if !cborEqual(decoded, tc.value) {
+			t.Errorf("unmarshal %s: got %#v, want %#v", tc.hex, decoded, tc.value)
+		}
+	}
+}
+
+// cborEqual compares decoded values, treating small uint64s as the int64s
+// generic decoding produces.
+func cborEqual(got, want any) bool {
+	if u, ok := want.(uint64); ok && u <= math.MaxInt64 {
+		want = int64(u)
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func TestCBOR_DecodeOnlyVectors(t *testing.T) {
+	cases := []struct {
+		hex  string
+		want any
+	}{
+		{"f97e00", math.NaN()},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9fff", []any{}},
+		{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{"c074323031332d30332d32315432303a30343a30305a", "2013-03-21T20:04:00Z"},
+		{"c11a514b67b0", int64(1363896240)},
+		{"fa47c35000", 100000.0},
+		{"f7", nil},
+	}
+	for _, tc := range cases {
+		data, _ := hex.DecodeString(tc.hex)
+		var got any
+		if err := cborFormat.unmarshal(data, &got, DefaultMaxJSONDepth); err != nil {
+			t.Fatalf("unmarshal %s: %v", tc.hex, err)
+		}
+		if f, ok := tc.want.(float64); ok && math.IsNaN(f) {
+			if g, ok := got.(float64); !ok || !math.IsNaN(g) {
+				t.Errorf("unmarshal %s: got %#v, want NaN", tc.hex, got)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("unmarshal %s: got %#v, want %#v", tc.hex, got, tc.want)
+		}
+	}
+
+	var ts time.Time
+	data, _ := hex.DecodeString("c074323031332d30332d32315432303a30343a30305a")
+	if err := cborFormat.unmarshal(data, &ts, DefaultMaxJSONDepth); err != nil || !ts.Equal(time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)) {
+		t.Fatalf("expected tagged date string to decode into time.Time, got %v (%v)", ts, err)
+	}
+}
+
+func TestCBOR_RejectsMalformedInput(t *testing.T) {
+	for _, h := range []string{
+		"",                   // empty
+		"18",                 // missing argument byte
+		"62c3",               // truncated string
+		"9b00000000ffffffff", // array longer than the input
+		"1c",                 // reserved additional information
+		"ff",                 // stray break
+		"0101",               // trailing data
+		"5f01ff",             // non-string chunk
+	} {
+		data, _ := hex.DecodeString(h)
+		var v any
+		if err := cborFormat.unmarshal(data, &v, DefaultMaxJSONDepth); err == nil {
+			t.Errorf("expected error for %q, got %#v", h, v)
+		}
+	}
+
+	nested, _ := hex.DecodeString(strings.Repeat("81", 5) + "00")
+	var v any
+	if err := cborFormat.unmarshal(nested, &v, 4); err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("expected max depth error, got %v", err)
+	}
+}
+
+func TestCBORCodec_RoundTripsOverHTTP(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(_ context.Context, in binaryOrder) (binaryOrder, error) {
+		in.ID++
+		return in, nil
+	}, "/orders"), WithCodec[binaryOrder, binaryOrder](CBORCodec[binaryOrder, binaryOrder]{}))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	body, err := cborFormat.marshal(sampleBinaryOrder())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/cbor")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/cbor" {
+		t.Fatalf("expected 200 application/cbor, got %d %q: %x", rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes())
+	}
+	var got binaryOrder
+	if err := cborFormat.unmarshal(rec.Body.Bytes(), &got, DefaultMaxJSONDepth); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	want := sampleBinaryOrder()
+	want.ID++
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\n got %#v\nwant %#v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package httprpc
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseTrustedProxy parses a CIDR prefix or a single address.
+func parseTrustedProxy(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err //nolint:wrapcheck // caller adds context
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err //nolint:wrapcheck // caller adds context
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// clientIP returns the address of the client that sent r. When the peer is a
+// trusted proxy, X-Forwarded-For is walked from the right, skipping trusted
+// hops, and the first untrusted address is returned.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, ok := remoteAddrIP(r.RemoteAddr)
+	if !ok {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+	if !isTrustedProxy(peer, trusted) {
+		return peer.String()
+	}
+
+	forwarded := r.Header.Values("X-Forwarded-For")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hops := strings.Split(forwarded[i], ",")
+		for j := len(hops) - 1; j >= 0; j-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[j]))
+			if err != nil {
+				return peer.String()
+			}
+			peer = hop.Unmap()
+			if !isTrustedProxy(peer, trusted) {
+				return peer.String()
+			}
+		}
+	}
+	return peer.String()
+}
+
+func remoteAddrIP(remoteAddr string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(remoteAddr); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -54,7 +54,7 @@ func TestAdaptHandler_ReturnsAfterDecodeError(t *testing.T) {
 		return struct{}{}, nil
 	}
 
-	h := adaptHandler[struct{}, struct{}](codec, handler)
+	h := adaptHandler[struct{}, struct{}](codec, handler, handlerConfig{})
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
 	h.ServeHTTP(rec, req)
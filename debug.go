@@ -0,0 +1,328 @@
+package httprpc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"maps"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+//go:embed templates/debug/routes.html.tmpl
+var debugRoutesTemplate string
+
+var debugRoutesTmpl = template.Must(template.New("routes").Parse(debugRoutesTemplate))
+
+// RouteTable describes how the router dispatches requests.
+type RouteTable struct {
+	// AutoHead reports whether GET routes also answer HEAD; streaming
+	// endpoints never do.
+	AutoHead bool `json:"auto_head"`
+	// AutoOptions reports whether routes answer OPTIONS with their Allow header.
+	AutoOptions bool `json:"auto_options"`
+	Fallback    bool `json:"fallback"`
+	// Error is set when no table was built and the registrations do not compile.
+	Error  string      `json:"error,omitempty"`
+	Routes []RouteInfo `json:"routes"`
+}
+
+// RouteInfo describes a route of the compiled table: an endpoint, a mount, a
+// route of a mounted Router, or an automatic HEAD or OPTIONS handler.
+type RouteInfo struct {
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path"`
+	Name    string            `json:"name,omitempty"`
+	Mount   bool              `json:"mount,omitempty"`
+	Auto    bool              `json:"auto,omitempty"` // automatic HEAD or OPTIONS handler
+	Host    string            `json:"host,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	PathParams []PathParamDescription `json:"path_params,omitempty"`
+	MetaFields []MetaFieldInfo        `json:"meta_fields,omitempty"`
+
+	// Middlewares is the effective chain, outermost first.
+	Middlewares []MiddlewareInfo `json:"middlewares,omitempty"`
+
+	Consumes []string   `json:"consumes,omitempty"`
+	Produces []string   `json:"produces,omitempty"`
+	Stream   StreamKind `json:"stream,omitempty"`
+
+	Req  string `json:"req,omitempty"`
+	Meta string `json:"meta,omitempty"`
+	Res  string `json:"res,omitempty"`
+
+	Handler HandlerSource `json:"handler"`
+}
+
+// MetaFieldInfo describes a meta struct field bound to a path param or header.
+type MetaFieldInfo struct {
+	Field     string `json:"field"`
+	Type      string `json:"type"`
+	Source    string `json:"source"` // "path", "header", "cookie", "query" or "remote"
+	Name      string `json:"name"`
+	Omitempty bool   `json:"omitempty,omitempty"`
+}
+
+// MiddlewareInfo describes a middleware in an endpoint's chain.
+type MiddlewareInfo struct {
+	Func     string `json:"func"`
+	Priority int    `json:"priority"`
+	Group    string `json:"group"` // prefix of the group it was added to
+}
+
+// HandlerSource locates the Go function handling an endpoint.
+type HandlerSource struct {
+	Func string `json:"func"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (s HandlerSource) String() string {
+	if s.File == "" {
+		return s.Func
+	}
+	return fmt.Sprintf("%s (%s:%d)", s.Func, s.File, s.Line)
+}
+
+// Routes returns the route table the router serves: the one built last by
+// Handler or LiveHandler.Reload, or else one compiled from the current
+// registrations. Routes are in dispatch order: trees with host/header matchers
+// first, then each tree by lookup priority (static segments before params
+// before catch-alls). Automatic HEAD and OPTIONS handlers have Auto set, and
+// the routes of a mounted Router follow its mount.
+func (r *Router) Routes() RouteTable {
+	out := RouteTable{
+		AutoHead:    !r.disableAutoHead,
+		AutoOptions: !r.disableAutoOptions,
+		Fallback:    r.fallback != nil,
+		Routes:      []RouteInfo{},
+	}
+	table := r.table.Load()
+	if table == nil {
+		var err error
+		if table, err = r.compile(false); err != nil {
+			out.Error = err.Error()
+			return out
+		}
+	}
+	out.Routes = table.describe(routeScope{})
+	return out
+}
+
+// routeScope places the routes of a mounted Router under its mount.
+type routeScope struct {
+	mounted     bool
+	prefix      string
+	host        string
+	headers     map[string]string
+	middlewares []MiddlewareInfo
+}
+
+func (s routeScope) apply(info RouteInfo) RouteInfo {
+	if !s.mounted {
+		return info
+	}
+	info.Path = normalizeRoutePath(s.prefix + info.Path)
+	if info.Host == "" {
+		info.Host = s.host
+	}
+	if s.headers != nil {
+		headers := maps.Clone(s.headers)
+		maps.Copy(headers, info.Headers)
+		info.Headers = headers
+	}
+	info.Middlewares = append(slices.Clone(s.middlewares), info.Middlewares...)
+	return info
+}
+
+// describe lists the table's routes in dispatch order. The entries a pattern
+// with optional params produces are listed once.
+func (t *routeTable) describe(scope routeScope) []RouteInfo {
+	type routeKey struct {
+		tree         *routeTree
+		path, method string
+	}
+	t.root.mu.RLock()
+	defer t.root.mu.RUnlock()
+	seen := map[routeKey]bool{}
+	var out []RouteInfo
+	for _, tree := range t.trees {
+		tree.root.walk(func(entry *routeEntry) {
+			m := entry.methods
+			for _, method := range slices.Sorted(maps.Keys(m.byMethod)) {
+				key := routeKey{tree: tree, path: entry.pattern.path, method: method}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				out = append(out, scope.apply(t.describeMethod(tree, m, method)))
+			}
+			if m.mountSource == nil {
+				return
+			}
+			mount := scope.apply(describeEndpoint(m.mountSource))
+			out = append(out, mount)
+			if m.mountTable != nil {
+				out = append(out, m.mountTable.describe(routeScope{
+					mounted:     true,
+					prefix:      strings.TrimSuffix(mount.Path, "/"),
+					host:        mount.Host,
+					headers:     mount.Headers,
+					middlewares: mount.Middlewares,
+				})...)
+			}
+		})
+	}
+	return out
+}
+
+// describeMethod describes the handler for method, which is registered or
+// one of the automatic HEAD and OPTIONS handlers.
+func (t *routeTable) describeMethod(tree *routeTree, m *routeMethods, method string) RouteInfo {
+	if e := m.sources[method]; e != nil {
+		return describeEndpoint(e)
+	}
+	src := m.sources[http.MethodGet]
+	if src == nil {
+		src = m.sources[slices.Sorted(maps.Keys(m.sources))[0]]
+	}
+	info := RouteInfo{
+		Method:     method,
+		Path:       normalizeRoutePath(src.Path),
+		Auto:       true,
+		Host:       tree.match.hostPattern(),
+		Headers:    tree.match.headerMap(),
+		PathParams: describePathParams(src.Path),
+	}
+	if method == http.MethodHead {
+		info.Middlewares = describeMiddlewares(src.Group)
+		info.Handler = src.Source
+	} else {
+		info.Middlewares = describeMiddlewares(optionsGroup(m, t.root))
+	}
+	return info
+}
+
+func describeEndpoint(e *endpoint) RouteInfo {
+	var match *groupMatch
+	if e.Group != nil {
+		match = e.Group.match
+	}
+	info := RouteInfo{
+		Method:      e.Method,
+		Path:        normalizeRoutePath(e.Path),
+		Mount:       e.Mount,
+		Host:        match.hostPattern(),
+		Headers:     match.headerMap(),
+		PathParams:  describePathParams(e.Path),
+		Middlewares: describeMiddlewares(e.Group),
+		Handler:     e.Source,
+	}
+	if m := e.Meta; m != nil {
+		info.Name = m.Name
+		info.Consumes = m.Consumes
+		info.Produces = m.Produces
+		info.Stream = m.Stream
+		info.Req = typeRef(m.Req).String
+		info.Meta = typeRef(m.Meta).String
+		info.Res = typeRef(m.Res).String
+		info.MetaFields = describeMetaFields(m.Meta)
+	}
+	if e.Mount {
+		info.Handler = HandlerSource{Func: fmt.Sprintf("%T", e.Handler)}
+	}
+	return info
+}
+
+// DebugHandler serves the route table as an HTML page, or as JSON when the
+// request has ?format=json or accepts application/json. It shows the table
+// returned by Routes at request time and is meant to be mounted on an internal
+// path.
+func (r *Router) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		table := r.Routes()
+		if req.URL.Query().Get("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(table); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := debugRoutesTmpl.Execute(w, table); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	})
+}
+
+// describeMiddlewares returns the group's effective middleware chain, outermost first.
+func describeMiddlewares(group *EndpointGroup) []MiddlewareInfo {
+	owner := map[*MiddlewareWithPriority]string{}
+	for g := group; g != nil; g = g.parent {
+		for _, mw := range g.Middlewares {
+			owner[mw] = normalizeRoutePath(g.Prefix)
+		}
+	}
+	ordered := orderMiddlewares(collectMiddlewares(group))
+	out := make([]MiddlewareInfo, 0, len(ordered))
+	for i := len(ordered) - 1; i >= 0; i-- {
+		mw := ordered[i]
+		if mw == nil || mw.Middleware == nil {
+			continue
+		}
+		out = append(out, MiddlewareInfo{
+			Func:     funcSource(mw.Middleware).Func,
+			Priority: mw.Priority,
+			Group:    owner[mw],
+		})
+	}
+	return out
+}
+
+func describeMetaFields(meta reflect.Type) []MetaFieldInfo {
+	if meta == nil || meta.Kind() != reflect.Struct {
+		return nil
+	}
+	var out []MetaFieldInfo
+	for i := range meta.NumField() {
+		field := meta.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		for _, source := range metaTagSources {
+			tag, err := parseMetaTag(meta, field, source.key, source.snakeCase)
+			if err != nil || !tag.found || tag.skip {
+				continue
+			}
+			out = append(out, MetaFieldInfo{
+				Field:     field.Name,
+				Type:      field.Type.String(),
+				Source:    source.key,
+				Name:      tag.name,
+				Omitempty: tag.omitempty,
+			})
+		}
+	}
+	return out
+}
+
+// funcSource returns the name and definition site of fn, which must be a func value.
+func funcSource(fn any) HandlerSource {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return HandlerSource{}
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return HandlerSource{}
+	}
+	file, line := f.FileLine(f.Entry())
+	return HandlerSource{Func: f.Name(), File: file, Line: line}
+}
@@ -0,0 +1,172 @@
+package httprpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type debugMeta struct {
+	ID    int    `path:"id"`
+	Trace string `header:"x-trace-id,omitempty"`
+}
+
+func debugGetUser(context.Context, struct{}, debugMeta) (string, error) {
+	return "", nil
+}
+
+func TestDebugHandler(t *testing.T) {
+	r := New()
+	outer := func(next http.Handler) http.Handler { return next }
+	inner := func(next http.Handler) http.Handler { return next }
+	r.Use(inner)
+	api := r.Group("/api")
+	api.Use(outer, Priority(10))
+	RegisterHandlerM(api, GETM(debugGetUser, "/users/:id<int>"), WithNameWithMeta[struct{}, debugMeta, string]("user"))
+	r.Host("admin.example.com").Mount("/static", http.NotFoundHandler())
+	r.Mount("/debug/routes", r.DebugHandler())
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/routes?format=json", http.NoBody))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON, got %q", ct)
+	}
+	var table RouteTable
+	if err := json.NewDecoder(rec.Body).Decode(&table); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !table.AutoHead || !table.AutoOptions || table.Fallback {
+		t.Fatalf("unexpected router flags: %+v", table)
+	}
+	var got []string
+	for _, route := range table.Routes {
+		got = append(got, describeRouteRow(route))
+	}
+	want := []string{
+		"mount /static admin.example.com",
+		"GET /api/users/:id<int>",
+		"HEAD /api/users/:id<int> auto",
+		"OPTIONS /api/users/:id<int> auto",
+		"mount /debug/routes",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected routes:\n got %q\nwant %q", got, want)
+	}
+
+	var user RouteInfo
+	for _, route := range table.Routes {
+		if route.Name == "user" {
+			user = route
+		}
+	}
+	if user.Method != http.MethodGet || user.Path != "/api/users/:id<int>" {
+		t.Fatalf("unexpected user route: %+v", user)
+	}
+	if len(user.Middlewares) != 2 || user.Middlewares[0].Priority != 10 || user.Middlewares[0].Group != "/api" || user.Middlewares[1].Group != "/" {
+		t.Fatalf("unexpected middleware chain: %+v", user.Middlewares)
+	}
+	if !strings.Contains(user.Middlewares[0].Func, "TestDebugHandler") {
+		t.Fatalf("expected middleware func name, got %q", user.Middlewares[0].Func)
+	}
+	if len(user.Consumes) == 0 || len(user.Produces) == 0 {
+		t.Fatalf("expected codec content types, got %+v", user)
+	}
+	wantFields := []MetaFieldInfo{
+		{Field: "ID", Type: "int", Source: "path", Name: "id"},
+		{Field: "Trace", Type: "string", Source: "header", Name: "x-trace-id", Omitempty: true},
+	}
+	if len(user.MetaFields) != len(wantFields) {
+		t.Fatalf("unexpected meta fields: %+v", user.MetaFields)
+	}
+	for i, want := range wantFields {
+		if user.MetaFields[i] != want {
+			t.Fatalf("meta field %d: expected %+v, got %+v", i, want, user.MetaFields[i])
+		}
+	}
+	if !strings.HasSuffix(user.Handler.Func, ".debugGetUser") || !strings.HasSuffix(user.Handler.File, "debug_test.go") || user.Handler.Line == 0 {
+		t.Fatalf("unexpected handler source: %+v", user.Handler)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/routes", http.NoBody))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected HTML, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"/api/users/:id&lt;int&gt;", "debugGetUser", "x-trace-id"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected HTML to contain %q", want)
+		}
+	}
+}
+
+func describeRouteRow(route RouteInfo) string {
+	row := route.Method + " " + route.Path
+	if route.Mount {
+		row = "mount " + route.Path
+	}
+	if route.Host != "" {
+		row += " " + route.Host
+	}
+	if route.Auto {
+		row += " auto"
+	}
+	return row
+}
+
+func TestRoutes_CompiledTable(t *testing.T) {
+	noop := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	sub := New(WithoutAutoOptions())
+	RegisterHandler(sub.EndpointGroup, GET(noop, "/items/:id"))
+	RegisterHandler(sub.EndpointGroup, GET(noop, "/items/latest"))
+
+	r := New(WithoutAutoOptions())
+	RegisterHandler(r.EndpointGroup, POST(noop, "/users/:id?"))
+	RegisterHandler(r.EndpointGroup, GET(noop, "/users/me"))
+	r.Host("admin.example.com").Mount("/v2", sub)
+
+	rows := func() []string {
+		var out []string
+		for _, route := range r.Routes().Routes {
+			out = append(out, describeRouteRow(route))
+		}
+		return out
+	}
+	want := []string{
+		"mount /v2 admin.example.com",
+		"GET /v2/items/latest admin.example.com",
+		"HEAD /v2/items/latest admin.example.com auto",
+		"GET /v2/items/:id admin.example.com",
+		"HEAD /v2/items/:id admin.example.com auto",
+		"POST /users/:id?",
+		"GET /users/me",
+		"HEAD /users/me auto",
+	}
+	if got := rows(); !slices.Equal(got, want) {
+		t.Fatalf("unexpected routes before build:\n got %q\nwant %q", got, want)
+	}
+
+	live, err := r.LiveHandler()
+	if err != nil {
+		t.Fatalf("live handler error: %v", err)
+	}
+	r.Unregister(http.MethodGet, "/users/me")
+	if got := rows(); !slices.Equal(got, want) {
+		t.Fatalf("expected the served table until Reload:\n got %q\nwant %q", got, want)
+	}
+	if err := live.Reload(); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if got := rows(); !slices.Equal(got, want[:6]) {
+		t.Fatalf("unexpected routes after Reload: %q", got)
+	}
+}
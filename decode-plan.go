@@ -0,0 +1,422 @@
+package httprpc
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Decode plans hold the per-type results of tag parsing, so query, meta and
+// body decoding only touch the fields that can be set. They are compiled when a
+// handler is registered (or on first use) and cached by type.
+var (
+	queryPlans sync.Map // reflect.Type -> *queryPlan
+	metaPlans  sync.Map // reflect.Type -> *metaPlan
+	bodyPlans  sync.Map // reflect.Type -> *bodyPlan
+)
+
+type queryPlan struct {
+	fields      []queryField // scalar and slice fields, by dotted name
+	maps        []queryField // map[string]T fields, set from "name.<key>"
+	files       []queryField // *File and []*File fields, set from multipart forms
+	hasDefaults bool         // some field has a default tag
+	err         error
+}
+
+type queryField struct {
+	index []int // field index path from the request struct
+	name  string
+	rules fieldRules
+}
+
+type metaSource int
+
+const (
+	metaSourcePath metaSource = iota
+	metaSourceHeader
+	metaSourceCookie
+	metaSourceQuery
+	metaSourceRemote
+)
+
+// metaTagSources lists the struct tags a meta field can be bound with. A field
+// may use at most one of them.
+var metaTagSources = []struct {
+	key       string
+	source    metaSource
+	snakeCase bool
+}{
+	{"path", metaSourcePath, true},
+	{"header", metaSourceHeader, false},
+	{"cookie", metaSourceCookie, false},
+	{"query", metaSourceQuery, true},
+	{"remote", metaSourceRemote, false},
+}
+
+// Values accepted by the remote tag.
+const (
+	remoteIP   = "ip"   // client IP, resolved through trusted proxies
+	remoteAddr = "addr" // r.RemoteAddr as received
+)
+
+type metaPlan struct {
+	fields []metaField
+	err    error
+}
+
+type metaField struct {
+	index     int
+	source    metaSource
+	name      string // name as written in the tag
+	key       string // canonical header key
+	omitempty bool
+	rules     fieldRules
+}
+
+// compileRequestPlans compiles the decode and validation plans of a request
+// type now rather than on the first request. It reports invalid default, enum
+// and validate tags.
+func compileRequestPlans(t reflect.Type) error {
+	queryPlanFor(t)
+	if err := bodyPlanFor(t).err; err != nil {
+		return err
+	}
+	return validatePlanFor(t, false).err
+}
+
+func queryPlanFor(t reflect.Type) *queryPlan {
+	if p, ok := queryPlans.Load(t); ok {
+		plan, _ := p.(*queryPlan)
+		return plan
+	}
+	p, _ := queryPlans.LoadOrStore(t, compileQueryPlan(t))
+	plan, _ := p.(*queryPlan)
+	return plan
+}
+
+func metaPlanFor(t reflect.Type) *metaPlan {
+	if p, ok := metaPlans.Load(t); ok {
+		plan, _ := p.(*metaPlan)
+		return plan
+	}
+	p, _ := metaPlans.LoadOrStore(t, compileMetaPlan(t))
+	plan, _ := p.(*metaPlan)
+	return plan
+}
+
+func compileQueryPlan(t reflect.Type) *queryPlan {
+	if t == nil || t.Kind() != reflect.Struct {
+		kind := reflect.Invalid
+		if t != nil {
+			kind = t.Kind()
+		}
+		return &queryPlan{err: fmt.Errorf("decode query: request type %s must be a struct", kind)}
+	}
+
+	plan := &queryPlan{}
+	b := queryPlanBuilder{plan: plan, names: map[string]int{}, visiting: map[reflect.Type]bool{}}
+	if err := b.addFields(t, nil, ""); err != nil {
+		return &queryPlan{err: err}
+	}
+	plan.hasDefaults = slices.ContainsFunc(plan.fields, func(f queryField) bool { return f.rules.hasDefault() })
+	return plan
+}
+
+type queryPlanBuilder struct {
+	plan     *queryPlan
+	names    map[string]int // leaf name -> position in plan.fields
+	visiting map[reflect.Type]bool
+}
+
+// addFields adds the fields of struct t. Embedded structs without an explicit
+// query/json name are flattened; nested structs add "prefix.name." keys.
+func (b *queryPlanBuilder) addFields(t reflect.Type, index []int, prefix string) error {
+	if b.visiting[t] {
+		return nil // recursive type; stop descending
+	}
+	b.visiting[t] = true
+	defer delete(b.visiting, t)
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		ft := deref(field.Type)
+		// Like encoding/json, promote fields of unexported embedded structs
+		// unless they are behind a pointer that could not be allocated.
+		promoted := field.Anonymous && ft.Kind() == reflect.Struct &&
+			(field.IsExported() || field.Type.Kind() != reflect.Pointer)
+		if promoted && hasExplicitQueryName(field) {
+			promoted = false
+		}
+		if !field.IsExported() && !promoted {
+			continue
+		}
+		fieldIndex := append(slices.Clip(index), i)
+
+		if promoted {
+			if err := b.addFields(ft, fieldIndex, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, skip, err := queryFieldName(t, field)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		name = prefix + name
+
+		switch {
+		case isFileField(field.Type):
+			b.plan.files = append(b.plan.files, queryField{index: fieldIndex, name: name})
+		case ft.Kind() == reflect.Struct && !isQueryScalar(field.Type):
+			if err := b.addFields(ft, fieldIndex, name+"."); err != nil {
+				return err
+			}
+		case ft.Kind() == reflect.Map && ft.Key().Kind() == reflect.String:
+			b.plan.maps = append(b.plan.maps, queryField{index: fieldIndex, name: name})
+		default:
+			rules, err := parseFieldRules(t, field)
+			if err != nil {
+				return err
+			}
+			b.addLeaf(queryField{index: fieldIndex, name: name, rules: rules})
+		}
+	}
+	return nil
+}
+
+// addLeaf adds f unless a shallower field already uses its name, mirroring
+// encoding/json's handling of promoted fields.
+func (b *queryPlanBuilder) addLeaf(f queryField) {
+	if pos, ok := b.names[f.name]; ok {
+		if len(b.plan.fields[pos].index) <= len(f.index) {
+			return
+		}
+		b.plan.fields[pos] = f
+		return
+	}
+	b.names[f.name] = len(b.plan.fields)
+	b.plan.fields = append(b.plan.fields, f)
+}
+
+func hasExplicitQueryName(f reflect.StructField) bool {
+	for _, key := range []string{"query", "json"} {
+		if name, found, skip := tagName(f, key); found && (skip || name != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// isQueryScalar reports whether a struct type is decoded from a single value
+// (time.Time and encoding.TextUnmarshaler implementations).
+func isQueryScalar(t reflect.Type) bool {
+	t = deref(t)
+	if t.PkgPath() == "time" && t.Name() == "Time" {
+		return true
+	}
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+func compileMetaPlan(t reflect.Type) *metaPlan {
+	if t == nil {
+		return &metaPlan{}
+	}
+	if t.Kind() == reflect.Pointer {
+		return &metaPlan{err: fmt.Errorf("request meta type must be a struct (non-pointer)")}
+	}
+	if t.Kind() != reflect.Struct {
+		return &metaPlan{err: fmt.Errorf("request meta type %s must be a struct", t.Kind())}
+	}
+
+	plan := &metaPlan{}
+	seen := map[metaSource]map[string]struct{}{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && deref(field.Type).Kind() == reflect.Struct {
+			continue
+		}
+
+		var (
+			tag     metaTag
+			source  metaSource
+			tagName string
+		)
+		for _, s := range metaTagSources {
+			parsed, err := parseMetaTag(t, field, s.key, s.snakeCase)
+			if err != nil {
+				return &metaPlan{err: err}
+			}
+			if !parsed.found {
+				continue
+			}
+			if tag.found {
+				return &metaPlan{err: fmt.Errorf("%s.%s: cannot use both %s and %s tags", metaOwnerName(t), field.Name, tagName, s.key)}
+			}
+			tag, source, tagName = parsed, s.source, s.key
+		}
+		if !tag.found || tag.skip {
+			continue
+		}
+
+		if seen[source] == nil {
+			seen[source] = map[string]struct{}{}
+		}
+		if _, ok := seen[source][tag.name]; ok {
+			return &metaPlan{err: fmt.Errorf("%s tag %q is used more than once", tagName, tag.name)}
+		}
+		seen[source][tag.name] = struct{}{}
+
+		rules, err := parseFieldRules(t, field)
+		if err != nil {
+			return &metaPlan{err: err}
+		}
+		f := metaField{index: i, source: source, name: tag.name, omitempty: tag.omitempty, rules: rules}
+		if source == metaSourceHeader {
+			f.key = http.CanonicalHeaderKey(tag.name)
+		}
+		if source == metaSourceRemote {
+			if tag.name != remoteIP && tag.name != remoteAddr {
+				return &metaPlan{err: fmt.Errorf("%s.%s: remote tag must be %q or %q", metaOwnerName(t), field.Name, remoteIP, remoteAddr)}
+			}
+			ft := deref(field.Type)
+			if ft.Kind() != reflect.String && !reflect.PointerTo(ft).Implements(textUnmarshalerType) {
+				return &metaPlan{err: fmt.Errorf("%s.%s: remote tag must bind a string or encoding.TextUnmarshaler", metaOwnerName(t), field.Name)}
+			}
+		}
+		plan.fields = append(plan.fields, f)
+	}
+	return plan
+}
+
+type bodyPlan struct {
+	fields []bodyField
+	err    error
+}
+
+type bodyField struct {
+	index []int
+	name  string // dotted JSON name, for errors
+	rules fieldRules
+}
+
+func bodyPlanFor(t reflect.Type) *bodyPlan {
+	if p, ok := bodyPlans.Load(t); ok {
+		plan, _ := p.(*bodyPlan)
+		return plan
+	}
+	p, _ := bodyPlans.LoadOrStore(t, compileBodyPlan(t))
+	plan, _ := p.(*bodyPlan)
+	return plan
+}
+
+func compileBodyPlan(t reflect.Type) *bodyPlan {
+	plan := &bodyPlan{}
+	t = deref(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return plan
+	}
+	if err := plan.addFields(t, nil, "", map[reflect.Type]bool{}); err != nil {
+		return &bodyPlan{err: err}
+	}
+	return plan
+}
+
+// addFields collects rules from t's fields, descending into embedded and
+// nested struct values. Fields behind pointers are left alone, so decoding
+// never allocates a nested struct the client did not send.
+func (p *bodyPlan) addFields(t reflect.Type, index []int, prefix string, visiting map[reflect.Type]bool) error {
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fieldIndex := append(slices.Clip(index), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && isPromotedJSONEmbed(field) {
+			if err := p.addFields(field.Type, fieldIndex, prefix, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, found, skip := tagName(field, "json")
+		if skip {
+			continue
+		}
+		if !found || name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+
+		if field.Type.Kind() == reflect.Struct && !isQueryScalar(field.Type) {
+			if err := p.addFields(field.Type, fieldIndex, name+".", visiting); err != nil {
+				return err
+			}
+			continue
+		}
+		rules, err := parseFieldRules(t, field)
+		if err != nil {
+			return err
+		}
+		if !rules.empty() {
+			p.fields = append(p.fields, bodyField{index: fieldIndex, name: name, rules: rules})
+		}
+	}
+	return nil
+}
+
+// applyDefaults sets defaulted fields of rv before the body is decoded over it.
+// A nil pointer request is allocated if the type has defaults.
+func (p *bodyPlan) applyDefaults(rv reflect.Value) {
+	if !slices.ContainsFunc(p.fields, func(f bodyField) bool { return f.rules.hasDefault() }) {
+		return
+	}
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	for _, f := range p.fields {
+		if f.rules.hasDefault() {
+			_ = setFromStrings(rv.FieldByIndex(f.index), f.rules.def)
+		}
+	}
+}
+
+// checkEnums checks the enum fields of a decoded body. Zero values are
+// treated as absent and not checked.
+func (p *bodyPlan) checkEnums(rv reflect.Value) error {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	for _, f := range p.fields {
+		v := rv.FieldByIndex(f.index)
+		if v.IsZero() {
+			continue
+		}
+		if err := f.rules.checkEnum(v); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 )
 
 // Codec defines the interface for encoding and decoding HTTP requests and responses.
@@ -30,6 +31,12 @@ func (c DefaultCodec[Req, Res]) Produces() []string { return []string{"applicati
 // DecodeBody decodes the request body into the request type using JSON.
 func (c DefaultCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
 	var req Req
+	plan := bodyPlanFor(reflect.TypeFor[Req]())
+	if plan.err != nil {
+		return req, fmt.Errorf("decode request: %w", plan.err)
+	}
+	rv := reflect.ValueOf(&req).Elem()
+	plan.applyDefaults(rv)
 	if r.Body == nil {
 		return req, nil
 	}
@@ -42,6 +49,9 @@ func (c DefaultCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
 	if err != nil {
 		return req, fmt.Errorf("decode request: %w", err)
 	}
+	if err := plan.checkEnums(rv); err != nil {
+		return req, fmt.Errorf("decode request: %w", err)
+	}
 	return req, nil
 }
 
@@ -51,27 +61,30 @@ func (c DefaultCodec[Req, Res]) DecodeQuery(r *http.Request) (Req, error) {
 }
 
 // Encode encodes the response into the HTTP response writer.
+// A Response is unwrapped into its status, headers, cookies and body.
 func (c DefaultCodec[Req, Res]) Encode(w http.ResponseWriter, res Res) error {
+	body, status := unwrapResponse(w, res, c.Status)
+	if !bodyAllowed(status) {
+		w.WriteHeader(status)
+		return nil
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if c.Status != 0 {
-		w.WriteHeader(c.Status)
+	if status != 0 {
+		w.WriteHeader(status)
 	}
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		return fmt.Errorf("encode response: %w", err)
 	}
 	return nil
 }
 
-// EncodeError encodes an error into the HTTP response writer.
+// EncodeError encodes an error as RFC 9457 problem details
+// (application/problem+json). See Problem for how errors are mapped.
 func (c DefaultCodec[Req, Res]) EncodeError(w http.ResponseWriter, err error) error {
-	w.Header().Set("Content-Type", "application/json")
-	status := http.StatusInternalServerError
-	var se StatusError
-	if errors.As(err, &se) && se.Status != 0 {
-		status = se.Status
-	}
-	w.WriteHeader(status)
-	if encErr := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); encErr != nil {
+	p := problemFor(err)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	if encErr := json.NewEncoder(w).Encode(p); encErr != nil {
 		return fmt.Errorf("encode error response: %w", encErr)
 	}
 	return nil
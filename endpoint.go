@@ -1,9 +1,13 @@
 package httprpc
 
 import (
-	"log/slog"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/netip"
 	"reflect"
+	"slices"
+	"sync"
 )
 
 // Endpoint represents an HTTP endpoint with a handler, path, and method.
@@ -126,6 +130,15 @@ type endpoint struct {
 	Method  string
 	Handler http.Handler
 	Group   *EndpointGroup
+	// Mount marks a mounted subtree; Handler serves every method under Path.
+	Mount bool
+	// NoAutoHead keeps the router from answering HEAD with this GET handler,
+	// for streams that would otherwise run until the client disconnects.
+	NoAutoHead bool
+	// Meta is the endpoint's entry in the root group's Metas (nil for mounts).
+	Meta *EndpointMeta
+	// Source locates the typed handler function, for DebugHandler.
+	Source HandlerSource
 }
 
 // EndpointGroup groups endpoints with a common prefix and middlewares.
@@ -136,10 +149,106 @@ type EndpointGroup struct {
 
 	root   *EndpointGroup
 	parent *EndpointGroup
+	match  *groupMatch
 
 	Metas []*EndpointMeta
 
+	// mu guards Handlers, Metas, sealed and errs on the root group, and the
+	// Middlewares of every group of the router.
+	mu     sync.RWMutex
 	sealed bool
+	strict bool
+	errs   []error
+
+	// trustedProxies is set on the root group by WithTrustedProxies.
+	trustedProxies []netip.Prefix
+	// redactErrors is set on the root group by WithRedactedErrors.
+	redactErrors bool
+	// errorMaps holds the MapError mappings of all groups, on the root group.
+	errorMaps []errorMapping
+}
+
+// errSealed is reported when registering on a router whose handler was already built.
+var errSealed = errors.New("router handler already built")
+
+func (eg *EndpointGroup) rootGroup() *EndpointGroup {
+	if eg.root == nil {
+		return eg
+	}
+	return eg.root
+}
+
+// handlerConfig returns the settings for handlers registered on eg.
+func (eg *EndpointGroup) handlerConfig() handlerConfig {
+	root := eg.rootGroup()
+	return handlerConfig{trustedProxies: root.trustedProxies, redactErrors: root.redactErrors, group: eg}
+}
+
+// addEndpoint adds an endpoint and its meta (nil for mounts) to the root group.
+func (eg *EndpointGroup) addEndpoint(e *endpoint, meta *EndpointMeta) {
+	if eg.match != nil && eg.match.err != nil {
+		if e.Mount {
+			eg.registerError(fmt.Errorf("mount %s: %w", e.Path, eg.match.err))
+		} else {
+			eg.registerError(fmt.Errorf("register %s %s: %w", e.Method, e.Path, eg.match.err))
+		}
+		return
+	}
+	root := eg.rootGroup()
+	root.mu.Lock()
+	if root.sealed {
+		root.mu.Unlock()
+		if e.Mount {
+			eg.registerError(fmt.Errorf("mount %s: %w", e.Path, errSealed))
+		} else {
+			eg.registerError(fmt.Errorf("register %s %s: %w", e.Method, e.Path, errSealed))
+		}
+		return
+	}
+	e.Meta = meta
+	root.Handlers = append(root.Handlers, e)
+	if meta != nil {
+		root.Metas = append(root.Metas, meta)
+	}
+	root.mu.Unlock()
+}
+
+// snapshot returns the currently registered endpoints and metas.
+func (eg *EndpointGroup) snapshot() ([]*endpoint, []*EndpointMeta) {
+	root := eg.rootGroup()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	return slices.Clone(root.Handlers), slices.Clone(root.Metas)
+}
+
+// registerError records a registration error on the root group.
+// In strict mode it panics instead.
+func (eg *EndpointGroup) registerError(err error) {
+	root := eg.rootGroup()
+	if root.strict {
+		panic(err)
+	}
+	root.mu.Lock()
+	root.errs = append(root.errs, err)
+	root.mu.Unlock()
+}
+
+// registrationErr returns the errors recorded during registration, if any.
+func (eg *EndpointGroup) registrationErr() error {
+	root := eg.rootGroup()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	return errors.Join(root.errs...)
+}
+
+// takeRegistrationErr returns and clears the errors recorded during registration.
+func (eg *EndpointGroup) takeRegistrationErr() error {
+	root := eg.rootGroup()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	err := errors.Join(root.errs...)
+	root.errs = nil
+	return err
 }
 
 // Group creates a subgroup with the given prefix.
@@ -149,6 +258,7 @@ func (eg *EndpointGroup) Group(prefix string) *EndpointGroup {
 		Middlewares: []*MiddlewareWithPriority{},
 		root:        eg.root,
 		parent:      eg,
+		match:       eg.match,
 	}
 }
 
@@ -160,6 +270,7 @@ type RegisterOption[Req, Res any] interface {
 type registerOptions[Req, Res any] struct {
 	codec       Codec[Req, Res]
 	middlewares []HandlerMiddleware[Req, Res]
+	name        string
 }
 
 type registerOptionFunc[Req, Res any] func(*registerOptions[Req, Res])
@@ -174,6 +285,7 @@ type RegisterOptionWithMeta[Req, Meta, Res any] interface {
 type registerOptionsWithMeta[Req, Meta, Res any] struct {
 	codec       Codec[Req, Res]
 	middlewares []HandlerWithMetaMiddleware[Req, Meta, Res]
+	name        string
 }
 
 type registerOptionWithMetaFunc[Req, Meta, Res any] func(*registerOptionsWithMeta[Req, Meta, Res])
@@ -223,19 +335,6 @@ func WithMetaMiddlewares[Req, Meta, Res any](middlewares ...HandlerWithMetaMiddl
 
 // RegisterHandler registers an endpoint with the endpoint group.
 func RegisterHandler[Req, Res any](eg *EndpointGroup, in Endpoint[Req, Res], opts ...RegisterOption[Req, Res]) {
-	root := eg.root
-	if root == nil {
-		root = eg
-	}
-	if root.sealed {
-		slog.Error("cannot register handlers after handler is built", "method", in.Method, "path", in.Path)
-		return
-	}
-	if root.sealed {
-		slog.Error("cannot register handlers after handler is built", "method", in.Method, "path", in.Path)
-		return
-	}
-
 	o := registerOptions[Req, Res]{
 		codec: DefaultCodec[Req, Res]{},
 	}
@@ -245,6 +344,11 @@ func RegisterHandler[Req, Res any](eg *EndpointGroup, in Endpoint[Req, Res], opt
 		}
 	}
 
+	if err := compileRequestPlans(reflect.TypeFor[Req]()); err != nil {
+		eg.registerError(fmt.Errorf("register %s %s: invalid request %s: %w", in.Method, eg.Prefix+in.Path, reflect.TypeFor[Req](), err))
+		return
+	}
+
 	codec := o.codec
 	handler := in.Handler
 	for i := len(o.middlewares) - 1; i >= 0; i-- {
@@ -256,13 +360,6 @@ func RegisterHandler[Req, Res any](eg *EndpointGroup, in Endpoint[Req, Res], opt
 	}
 
 	path := eg.Prefix + in.Path
-	root.Handlers = append(root.Handlers, &endpoint{
-		Path:    eg.Prefix + in.Path,
-		Method:  in.Method,
-		Handler: adaptHandler(codec, handler),
-		Group:   eg,
-	})
-
 	var consumes, produces []string
 	if ct, ok := any(codec).(interface {
 		Consumes() []string
@@ -272,11 +369,20 @@ func RegisterHandler[Req, Res any](eg *EndpointGroup, in Endpoint[Req, Res], opt
 		produces = ct.Produces()
 	}
 
-	root.Metas = append(root.Metas, &EndpointMeta{
+	eg.addEndpoint(&endpoint{
+		Path:    path,
+		Method:  in.Method,
+		Source:  funcSource(in.Handler),
+		Handler: adaptHandler(codec, handler, eg.handlerConfig()),
+		Group:   eg,
+	}, &EndpointMeta{
+		Name:     o.name,
 		Method:   in.Method,
 		Path:     path,
+		Host:     eg.match.hostPattern(),
+		Headers:  eg.match.headerMap(),
 		Req:      reflect.TypeFor[Req](),
-		Res:      reflect.TypeFor[Res](),
+		Res:      responseBodyType(reflect.TypeFor[Res]()),
 		Consumes: consumes,
 		Produces: produces,
 	})
@@ -284,29 +390,11 @@ func RegisterHandler[Req, Res any](eg *EndpointGroup, in Endpoint[Req, Res], opt
 
 // RegisterHandlerM registers an endpoint with typed metadata.
 func RegisterHandlerM[Req, Meta, Res any](eg *EndpointGroup, in EndpointWithMeta[Req, Meta, Res], opts ...RegisterOptionWithMeta[Req, Meta, Res]) {
-	root := eg.root
-	if root == nil {
-		root = eg
-	}
-	if root.sealed {
-		slog.Error("cannot register handlers after handler is built", "method", in.Method, "path", in.Path)
-		return
-	}
-	if root.sealed {
-		slog.Error("cannot register handlers after handler is built", "method", in.Method, "path", in.Path)
-		return
-	}
-
 	metaType := reflect.TypeFor[Meta]()
-	if metaType != nil {
-		if metaType.Kind() == reflect.Pointer {
-			slog.Error("request meta type must be a struct (non-pointer)", "method", in.Method, "path", in.Path, "type", metaType.String())
-			return
-		}
-		if metaType.Kind() != reflect.Struct {
-			slog.Error("request meta type must be a struct", "method", in.Method, "path", in.Path, "type", metaType.String())
-			return
-		}
+	path := eg.Prefix + in.Path
+	if err := validateMetaType(metaType, path, eg.match.hostParams()); err != nil {
+		eg.registerError(fmt.Errorf("register %s %s: invalid request meta %s: %w", in.Method, path, metaType, err))
+		return
 	}
 
 	o := registerOptionsWithMeta[Req, Meta, Res]{
@@ -318,6 +406,11 @@ func RegisterHandlerM[Req, Meta, Res any](eg *EndpointGroup, in EndpointWithMeta
 		}
 	}
 
+	if err := compileRequestPlans(reflect.TypeFor[Req]()); err != nil {
+		eg.registerError(fmt.Errorf("register %s %s: invalid request %s: %w", in.Method, eg.Prefix+in.Path, reflect.TypeFor[Req](), err))
+		return
+	}
+
 	codec := o.codec
 	handler := in.Handler
 	for i := len(o.middlewares) - 1; i >= 0; i-- {
@@ -328,18 +421,6 @@ func RegisterHandlerM[Req, Meta, Res any](eg *EndpointGroup, in EndpointWithMeta
 		handler = mw(handler)
 	}
 
-	path := eg.Prefix + in.Path
-	if err := validateMetaType(metaType, path); err != nil {
-		slog.Error("invalid request meta", "method", in.Method, "path", path, "error", err)
-		return
-	}
-	root.Handlers = append(root.Handlers, &endpoint{
-		Path:    eg.Prefix + in.Path,
-		Method:  in.Method,
-		Handler: adaptHandlerWithMeta(codec, handler),
-		Group:   eg,
-	})
-
 	var consumes, produces []string
 	if ct, ok := any(codec).(interface {
 		Consumes() []string
@@ -349,12 +430,21 @@ func RegisterHandlerM[Req, Meta, Res any](eg *EndpointGroup, in EndpointWithMeta
 		produces = ct.Produces()
 	}
 
-	root.Metas = append(root.Metas, &EndpointMeta{
+	eg.addEndpoint(&endpoint{
+		Path:    path,
+		Method:  in.Method,
+		Source:  funcSource(in.Handler),
+		Handler: adaptHandlerWithMeta(codec, handler, eg.handlerConfig()),
+		Group:   eg,
+	}, &EndpointMeta{
+		Name:     o.name,
 		Method:   in.Method,
 		Path:     path,
+		Host:     eg.match.hostPattern(),
+		Headers:  eg.match.headerMap(),
 		Req:      reflect.TypeFor[Req](),
 		Meta:     metaType,
-		Res:      reflect.TypeFor[Res](),
+		Res:      responseBodyType(reflect.TypeFor[Res]()),
 		Consumes: consumes,
 		Produces: produces,
 	})
@@ -0,0 +1,98 @@
+package httprpc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// errorMapping turns handler errors that match into a StatusError.
+type errorMapping struct {
+	group  *EndpointGroup
+	match  func(error) bool
+	status int
+	code   string
+}
+
+// MapError maps handler errors that wrap target (per errors.Is) to a
+// StatusError with the given status and code, so handlers can return domain
+// errors as they are. Mappings apply to the group's endpoints and those of its
+// subgroups. Inner groups are consulted first; within a group, the first
+// matching mapping wins. Errors that already are a StatusError, *Problem or
+// ValidationError are left alone.
+//
+// Codes are exported to the generated TypeScript client as the ErrorCode union.
+func (eg *EndpointGroup) MapError(target error, status int, code string) {
+	if target == nil {
+		eg.registerError(fmt.Errorf("map error %q: nil target", code))
+		return
+	}
+	eg.addErrorMapping(func(err error) bool { return errors.Is(err, target) }, status, code)
+}
+
+// MapErrorFunc is like MapError for errors match reports true for.
+func (eg *EndpointGroup) MapErrorFunc(match func(error) bool, status int, code string) {
+	if match == nil {
+		eg.registerError(fmt.Errorf("map error %q: nil match func", code))
+		return
+	}
+	eg.addErrorMapping(match, status, code)
+}
+
+// MapErrorAs is like MapError for errors that contain an E (per errors.As),
+// typically a custom error struct.
+func MapErrorAs[E error](eg *EndpointGroup, status int, code string) {
+	eg.addErrorMapping(func(err error) bool {
+		var target E
+		return errors.As(err, &target)
+	}, status, code)
+}
+
+func (eg *EndpointGroup) addErrorMapping(match func(error) bool, status int, code string) {
+	if status < 400 || status > 599 {
+		eg.registerError(fmt.Errorf("map error %q: status %d is not an error status", code, status))
+		return
+	}
+	root := eg.rootGroup()
+	root.mu.Lock()
+	root.errorMaps = append(root.errorMaps, errorMapping{group: eg, match: match, status: status, code: code})
+	root.mu.Unlock()
+}
+
+// mapError returns the StatusError the mappings of eg and its parents produce
+// for err, or err itself if none matches.
+func (eg *EndpointGroup) mapError(err error) error {
+	if isPublicError(err) {
+		return err
+	}
+	root := eg.rootGroup()
+	root.mu.RLock()
+	mappings := root.errorMaps
+	root.mu.RUnlock()
+	if len(mappings) == 0 {
+		return err
+	}
+	for g := eg; g != nil; g = g.parent {
+		for _, m := range mappings {
+			if m.group == g && m.match(err) {
+				return StatusError{Status: m.status, Err: err, Code: m.code}
+			}
+		}
+	}
+	return err
+}
+
+// errorCodes returns the codes of all error mappings, sorted.
+func (eg *EndpointGroup) errorCodes() []string {
+	root := eg.rootGroup()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	var codes []string
+	for _, m := range root.errorMaps {
+		if m.code != "" {
+			codes = append(codes, m.code)
+		}
+	}
+	slices.Sort(codes)
+	return slices.Compact(codes)
+}
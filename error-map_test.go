@@ -0,0 +1,129 @@
+package httprpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	errTestNotFound = errors.New("not found")
+	errTestGone     = errors.New("gone")
+)
+
+type testRateLimitError struct{ RetryAfter int }
+
+func (e *testRateLimitError) Error() string { return fmt.Sprintf("retry after %ds", e.RetryAfter) }
+
+func TestMapError(t *testing.T) {
+	r := New(WithRedactedErrors())
+	r.MapError(errTestNotFound, http.StatusNotFound, "not_found")
+	MapErrorAs[*testRateLimitError](r.EndpointGroup, http.StatusTooManyRequests, "rate_limited")
+
+	admin := r.Group("/admin")
+	admin.MapErrorFunc(func(err error) bool { return errors.Is(err, errTestNotFound) }, http.StatusForbidden, "hidden")
+	admin.MapError(errTestGone, http.StatusGone, "gone")
+
+	handler := func(_ context.Context, in struct {
+		Kind string `json:"kind"`
+	},
+	) (struct{}, error) {
+		switch in.Kind {
+		case "not_found":
+			return struct{}{}, fmt.Errorf("load user 7: %w", errTestNotFound)
+		case "rate":
+			return struct{}{}, fmt.Errorf("call upstream: %w", &testRateLimitError{RetryAfter: 3})
+		case "gone":
+			return struct{}{}, errTestGone
+		case "explicit":
+			return struct{}{}, StatusError{Status: http.StatusConflict, Err: errTestNotFound}
+		default:
+			return struct{}{}, errors.New("boom")
+		}
+	}
+	RegisterHandler(r.EndpointGroup, POST(handler, "/do"))
+	RegisterHandler(admin, POST(handler, "/do"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	tests := []struct {
+		path, kind string
+		status     int
+		code       string
+		detail     string
+	}{
+		{"/do", "not_found", http.StatusNotFound, "not_found", "load user 7: not found"},
+		{"/do", "rate", http.StatusTooManyRequests, "rate_limited", "call upstream: retry after 3s"},
+		{"/do", "gone", http.StatusInternalServerError, "", ""},
+		{"/do", "explicit", http.StatusConflict, "", "not found"},
+		{"/do", "other", http.StatusInternalServerError, "", ""},
+		{"/admin/do", "not_found", http.StatusForbidden, "hidden", "load user 7: not found"},
+		{"/admin/do", "rate", http.StatusTooManyRequests, "rate_limited", "call upstream: retry after 3s"},
+		{"/admin/do", "gone", http.StatusGone, "gone", "gone"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{"kind":"`+tt.kind+`"}`)))
+		var p Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s %s: decode problem: %v", tt.path, tt.kind, err)
+		}
+		code, _ := p.Extensions["code"].(string)
+		if rec.Code != tt.status || code != tt.code || p.Detail != tt.detail {
+			t.Fatalf("%s %s: got %d %q %q, want %d %q %q", tt.path, tt.kind, rec.Code, code, p.Detail, tt.status, tt.code, tt.detail)
+		}
+	}
+}
+
+func TestMapError_InvalidMappingsAreRegistrationErrors(t *testing.T) {
+	r := New()
+	r.MapError(nil, http.StatusNotFound, "nil_target")
+	r.MapErrorFunc(nil, http.StatusNotFound, "nil_func")
+	r.MapError(errTestNotFound, http.StatusOK, "ok")
+
+	_, err := r.Handler()
+	if err == nil {
+		t.Fatal("expected registration error")
+	}
+	for _, want := range []string{"nil_target", "nil_func", "status 200"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestRouterGenTS_ErrorCodeUnion(t *testing.T) {
+	r := New()
+	r.MapError(errTestNotFound, http.StatusNotFound, "not_found")
+	r.Group("/admin").MapError(errTestGone, http.StatusGone, "gone")
+	MapErrorAs[*testRateLimitError](r.EndpointGroup, http.StatusTooManyRequests, "rate_limited")
+	r.MapError(errTestGone, http.StatusGone, "gone")
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/ping"))
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	want := `export type ErrorCode = "gone" | "not_found" | "rate_limited"`
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("expected %q in generated client:\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := New().GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "export type ErrorCode = never") {
+		t.Fatalf("expected empty ErrorCode union:\n%s", buf.String())
+	}
+}
@@ -5,6 +5,12 @@ package httprpc
 type StatusError struct {
 	Status int
 	Err    error
+
+	// Code is a machine-readable error code, sent as the problem's "code" member.
+	Code string
+	// Details is sent as the problem's "details" member, typically a struct
+	// describing the failure.
+	Details any
 }
 
 func (e StatusError) Error() string {
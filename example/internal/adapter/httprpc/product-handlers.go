@@ -2,7 +2,7 @@ package httprpcadapter
 
 import (
 	"context"
-	"errors"
+	"net/http"
 
 	"github.com/behzade/httprpc"
 	"github.com/behzade/httprpc/example/internal/core/product"
@@ -21,10 +21,10 @@ func NewProductHandlers(module *product.Module) *ProductHandlers {
 // HTTP DTOs (only used at the transport layer).
 type (
 	ListProductsRequest struct {
-		Page          int    `json:"page" query:"page"`
-		PerPage       int    `json:"per_page" query:"per_page"`
-		SortField     string `json:"sort_field" query:"sort_field"`
-		SortDirection string `json:"sort_direction" query:"sort_direction"`
+		Page          int    `json:"page" query:"page" default:"1"`
+		PerPage       int    `json:"per_page" query:"per_page" default:"25"`
+		SortField     string `json:"sort_field" query:"sort_field" default:"name"`
+		SortDirection string `json:"sort_direction" query:"sort_direction" default:"ASC" enum:"ASC,DESC"`
 		Query         string `json:"query" query:"query"`
 	}
 
@@ -72,13 +72,16 @@ type (
 
 // Register mounts product endpoints under the provided group.
 func (h *ProductHandlers) Register(api *httprpc.EndpointGroup) {
+	api.MapError(domain.ErrInvalidArgument, http.StatusBadRequest, "invalid_argument")
+	api.MapError(domain.ErrNotFound, http.StatusNotFound, "not_found")
+
 	httprpc.RegisterHandler(
 		api,
 		httprpc.GET(
 			func(ctx context.Context, req ListProductsRequest) (ListProductsResponse, error) {
 				result, err := h.module.List(ctx, domain.ListProductsInput(req))
 				if err != nil {
-					return ListProductsResponse{}, mapError(err)
+					return ListProductsResponse{}, err
 				}
 				return ListProductsResponse{
 					Items: toProductDTOs(result.Items),
@@ -95,7 +98,7 @@ func (h *ProductHandlers) Register(api *httprpc.EndpointGroup) {
 			func(ctx context.Context, req GetProductRequest) (Product, error) {
 				p, err := h.module.Get(ctx, req.ID)
 				if err != nil {
-					return Product{}, mapError(err)
+					return Product{}, err
 				}
 				return toProductDTO(*p), nil
 			},
@@ -109,7 +112,7 @@ func (h *ProductHandlers) Register(api *httprpc.EndpointGroup) {
 			func(ctx context.Context, req CreateProductRequest) (Product, error) {
 				p, err := h.module.Create(ctx, domain.CreateProductInput(req))
 				if err != nil {
-					return Product{}, mapError(err)
+					return Product{}, err
 				}
 				return toProductDTO(*p), nil
 			},
@@ -123,7 +126,7 @@ func (h *ProductHandlers) Register(api *httprpc.EndpointGroup) {
 			func(ctx context.Context, req UpdateProductRequest) (Product, error) {
 				p, err := h.module.Update(ctx, domain.UpdateProductInput(req))
 				if err != nil {
-					return Product{}, mapError(err)
+					return Product{}, err
 				}
 				return toProductDTO(*p), nil
 			},
@@ -136,7 +139,7 @@ func (h *ProductHandlers) Register(api *httprpc.EndpointGroup) {
 		httprpc.DELETE(
 			func(ctx context.Context, req DeleteProductRequest) (DeleteProductResponse, error) {
 				if err := h.module.Delete(ctx, req.ID); err != nil {
-					return DeleteProductResponse{}, mapError(err)
+					return DeleteProductResponse{}, err
 				}
 				return DeleteProductResponse{ID: req.ID}, nil
 			},
@@ -163,14 +166,3 @@ func toProductDTOs(items []domain.Product) []Product {
 	}
 	return out
 }
-
-func mapError(err error) error {
-	switch {
-	case errors.Is(err, domain.ErrInvalidArgument):
-		return httprpc.StatusError{Status: 400, Err: err}
-	case errors.Is(err, domain.ErrNotFound):
-		return httprpc.StatusError{Status: 404, Err: err}
-	default:
-		return err
-	}
-}
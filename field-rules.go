@@ -0,0 +1,97 @@
+package httprpc
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fieldRules holds the `default` and `enum` tags of a field. Defaults are
+// applied when a value is missing; enum values restrict what a decoded value
+// may be. Both are checked against the field type when the plan is compiled.
+type fieldRules struct {
+	def  []string // default as strings, split on commas for slice fields
+	enum []string // allowed values, in canonical form
+}
+
+func (r fieldRules) hasDefault() bool { return r.def != nil }
+
+func (r fieldRules) empty() bool { return r.def == nil && r.enum == nil }
+
+// parseFieldRules reads the default and enum tags of f.
+func parseFieldRules(owner reflect.Type, f reflect.StructField) (fieldRules, error) {
+	var rules fieldRules
+	elem := deref(f.Type)
+	isSlice := elem.Kind() == reflect.Slice && elem.Elem().Kind() != reflect.Uint8
+	if isSlice {
+		elem = deref(elem.Elem())
+	}
+
+	if tag, ok := f.Tag.Lookup("enum"); ok {
+		for _, raw := range strings.Split(tag, ",") {
+			v := reflect.New(elem).Elem()
+			if err := setFromStrings(v, []string{strings.TrimSpace(raw)}); err != nil {
+				return fieldRules{}, fmt.Errorf("%s.%s: enum value %q: %w", metaOwnerName(owner), f.Name, raw, err)
+			}
+			rules.enum = append(rules.enum, formatRuleValue(v))
+		}
+	}
+
+	if tag, ok := f.Tag.Lookup("default"); ok {
+		rules.def = []string{tag}
+		if isSlice {
+			rules.def = strings.Split(tag, ",")
+		}
+		v := reflect.New(f.Type).Elem()
+		if err := setFromStrings(v, rules.def); err != nil {
+			return fieldRules{}, fmt.Errorf("%s.%s: default %q: %w", metaOwnerName(owner), f.Name, tag, err)
+		}
+		if err := rules.checkEnum(v); err != nil {
+			return fieldRules{}, fmt.Errorf("%s.%s: default %w", metaOwnerName(owner), f.Name, err)
+		}
+	}
+	return rules, nil
+}
+
+// checkEnum reports an error if v, or any element of slice v, is not one of
+// the allowed values. Nil pointers are not checked.
+func (r fieldRules) checkEnum(v reflect.Value) error {
+	if r.enum == nil {
+		return nil
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := range v.Len() {
+			if err := r.checkEnum(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s := formatRuleValue(v)
+	for _, allowed := range r.enum {
+		if s == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %s", s, strings.Join(r.enum, ", "))
+}
+
+// formatRuleValue formats a decoded scalar so enum values and request values
+// compare equal regardless of how they were spelled ("01" and "1").
+func formatRuleValue(v reflect.Value) string {
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			if b, err := m.MarshalText(); err == nil {
+				return string(b)
+			}
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
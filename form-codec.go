@@ -0,0 +1,240 @@
+package httprpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+)
+
+// DefaultMaxFormMemory is the multipart memory limit FormCodec uses when
+// MaxMemory is zero. Larger uploads are spooled to temporary files.
+const DefaultMaxFormMemory = 32 << 20
+
+// File is a file uploaded in a multipart form. Declare request fields as
+// *File or []*File; the form field name comes from the query/json tag.
+type File struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Header      textproto.MIMEHeader
+
+	fh *multipart.FileHeader
+}
+
+// Open returns a reader for the file contents. Small files are read from
+// memory, larger ones are streamed from a temporary file. Close it when done.
+// The contents are only available until the handler returns.
+func (f *File) Open() (multipart.File, error) {
+	if f == nil || f.fh == nil {
+		return nil, errors.New("httprpc: file has no contents")
+	}
+	file, err := f.fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", f.Filename, err)
+	}
+	return file, nil
+}
+
+var fileType = reflect.TypeFor[File]()
+
+// isFileField reports whether t is *File or []*File.
+func isFileField(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Pointer && t.Elem() == fileType
+}
+
+// FormCodec decodes application/x-www-form-urlencoded and multipart/form-data
+// bodies into Req with the same field rules as query decoding, and sets *File
+// and []*File fields from uploaded files. GET requests, responses and errors
+// are handled as in DefaultCodec.
+type FormCodec[Req any, Res any] struct {
+	DefaultCodec[Req, Res]
+
+	// MaxMemory is the number of multipart bytes kept in memory. Zero means DefaultMaxFormMemory.
+	MaxMemory int64
+	// MaxBytes limits the request body size. Zero means no limit.
+	MaxBytes int64
+	// MaxFileBytes limits the size of each uploaded file. Zero means no limit.
+	// The limit is enforced while the body is read, before a file is spooled.
+	MaxFileBytes int64
+}
+
+// Consumes returns the content types this codec can decode.
+func (c FormCodec[Req, Res]) Consumes() []string {
+	return []string{"multipart/form-data", "application/x-www-form-urlencoded"}
+}
+
+// DecodeBody decodes a form body into the request type.
+func (c FormCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
+	var req Req
+	plan := queryPlanFor(reflect.TypeFor[Req]())
+	if plan.err != nil {
+		return req, fmt.Errorf("decode form: %w", plan.err)
+	}
+	if r.Body != nil && c.MaxBytes > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, c.MaxBytes)
+	}
+
+	var (
+		mediaType string
+		params    map[string]string
+	)
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, ps, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return req, fmt.Errorf("decode form: %w", err)
+		}
+		mediaType, params = mt, ps
+	}
+	var err error
+	switch mediaType {
+	case "multipart/form-data":
+		maxMemory := c.MaxMemory
+		if maxMemory <= 0 {
+			maxMemory = DefaultMaxFormMemory
+		}
+		if c.MaxFileBytes > 0 && r.Body != nil && params["boundary"] != "" {
+			stop := limitMultipartFiles(r, params["boundary"], c.MaxFileBytes)
+			err = r.ParseMultipartForm(maxMemory)
+			stop()
+		} else {
+			err = r.ParseMultipartForm(maxMemory)
+		}
+	case "application/x-www-form-urlencoded", "":
+		err = r.ParseForm()
+	default:
+		return req, StatusError{
+			Status: http.StatusUnsupportedMediaType,
+			Err:    fmt.Errorf("decode form: unsupported content type %q", mediaType),
+		}
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return req, StatusError{Status: http.StatusRequestEntityTooLarge, Err: fmt.Errorf("decode form: %w", err)}
+		}
+		var fileTooLarge *fileTooLargeError
+		if errors.As(err, &fileTooLarge) {
+			return req, StatusError{Status: http.StatusRequestEntityTooLarge, Err: fileTooLarge}
+		}
+		return req, fmt.Errorf("decode form: %w", err)
+	}
+
+	rv := reflect.ValueOf(&req).Elem()
+	if err := decodeValues(rv, plan, r.PostForm, "form"); err != nil {
+		return req, err
+	}
+	if r.MultipartForm != nil {
+		if err := c.setFiles(rv, plan, r.MultipartForm); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+func (c FormCodec[Req, Res]) setFiles(rv reflect.Value, plan *queryPlan, form *multipart.Form) error {
+	for _, f := range plan.files {
+		headers := form.File[f.name]
+		if len(headers) == 0 {
+			continue
+		}
+		files := make([]*File, len(headers))
+		for i, fh := range headers {
+			files[i] = &File{
+				Filename:    fh.Filename,
+				ContentType: fh.Header.Get("Content-Type"),
+				Size:        fh.Size,
+				Header:      fh.Header,
+				fh:          fh,
+			}
+		}
+		v := fieldByIndexAlloc(rv, f.index)
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.ValueOf(files))
+		} else {
+			v.Set(reflect.ValueOf(files[0]))
+		}
+	}
+	return nil
+}
+
+// fileTooLargeError reports an uploaded file over FormCodec.MaxFileBytes.
+type fileTooLargeError struct {
+	field, filename string
+	limit           int64
+}
+
+func (e *fileTooLargeError) Error() string {
+	return fmt.Sprintf("decode form %s: file %q exceeds %d bytes", e.field, e.filename, e.limit)
+}
+
+// limitMultipartFiles replaces r.Body with a copy of the multipart body that
+// fails as soon as a file part exceeds limit bytes, so oversized uploads are
+// rejected before they are spooled rather than after. The returned stop func
+// must be called once the body has been parsed; it waits for the copy to end.
+func limitMultipartFiles(r *http.Request, boundary string, limit int64) (stop func()) {
+	body := r.Body
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(copyMultipartLimited(pw, multipart.NewReader(body, boundary), boundary, limit))
+	}()
+	r.Body = pr
+	return func() {
+		_ = pr.Close()
+		<-done
+		r.Body = body
+	}
+}
+
+// copyMultipartLimited re-encodes the parts of mr into w, unchanged except
+// that reading stops one byte past limit in any file part.
+func copyMultipartLimited(w io.Writer, mr *multipart.Reader, boundary string, limit int64) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for {
+		part, err := mr.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return mw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		dst, err := mw.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		if part.FileName() == "" {
+			if _, err := io.Copy(dst, part); err != nil {
+				return err
+			}
+			continue
+		}
+		n, err := io.Copy(dst, io.LimitReader(part, limit+1))
+		if err != nil {
+			return err
+		}
+		if n > limit {
+			return &fileTooLargeError{field: part.FormName(), filename: part.FileName(), limit: limit}
+		}
+	}
+}
+
+// removeMultipartFiles deletes the temporary files of a parsed multipart form.
+// The server only cleans up forms parsed on its own *http.Request, not on the
+// copies made for path params.
+func removeMultipartFiles(r *http.Request) {
+	if r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
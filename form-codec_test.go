@@ -0,0 +1,199 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type formUpload struct {
+	Title       string   `json:"title"`
+	Tags        []string `json:"tags"`
+	Avatar      *File    `json:"avatar"`
+	Attachments []*File  `json:"attachments"`
+}
+
+func multipartRequest(t *testing.T, fields map[string]string, files map[string][]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatalf("write field: %v", err)
+		}
+	}
+	for name, contents := range files {
+		for i, c := range contents {
+			fw, err := mw.CreateFormFile(name, name+strings.Repeat("x", i)+".txt")
+			if err != nil {
+				t.Fatalf("create form file: %v", err)
+			}
+			_, _ = fw.Write([]byte(c))
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close multipart writer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestFormCodec_DecodeURLEncoded(t *testing.T) {
+	codec := FormCodec[formUpload, struct{}]{}
+	form := url.Values{"title": {"hello"}, "tags": {"a", "b"}}
+	req := httptest.NewRequest(http.MethodPost, "/?title=ignored", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	got, err := codec.DecodeBody(req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Title != "hello" || len(got.Tags) != 2 || got.Avatar != nil {
+		t.Fatalf("unexpected decoded req: %+v", got)
+	}
+}
+
+func TestFormCodec_DecodeMultipartFiles(t *testing.T) {
+	codec := FormCodec[formUpload, struct{}]{MaxMemory: 1}
+	req := multipartRequest(t, map[string]string{"title": "report"}, map[string][]string{
+		"avatar":      {"png-bytes"},
+		"attachments": {"one", "two"},
+	})
+
+	got, err := codec.DecodeBody(req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	defer removeMultipartFiles(req)
+	if got.Title != "report" || got.Avatar == nil || len(got.Attachments) != 2 {
+		t.Fatalf("unexpected decoded req: %+v", got)
+	}
+	if got.Avatar.Filename != "avatar.txt" || got.Avatar.Size != int64(len("png-bytes")) {
+		t.Fatalf("unexpected avatar: %+v", got.Avatar)
+	}
+	f, err := got.Attachments[1].Open()
+	if err != nil {
+		t.Fatalf("open attachment: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	data, _ := io.ReadAll(f)
+	if string(data) != "two" {
+		t.Fatalf("unexpected attachment contents %q", data)
+	}
+}
+
+func TestFormCodec_Limits(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, formUpload) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/uploads"), WithCodec[formUpload, struct{}](FormCodec[formUpload, struct{}]{MaxFileBytes: 4}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := multipartRequest(t, nil, map[string][]string{"avatar": {"too large"}})
+	req.URL.Path = "/uploads"
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected %d, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/uploads", strings.NewReader(`{"title":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnsupportedMediaType, rec.Code, rec.Body.String())
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestFormCodec_MaxFileBytesStopsReading(t *testing.T) {
+	codec := FormCodec[formUpload, struct{}]{MaxFileBytes: 4}
+	req := multipartRequest(t, map[string]string{"title": "report"}, map[string][]string{
+		"avatar": {strings.Repeat("x", 4<<20)},
+	})
+	total := int(req.ContentLength)
+	body := &countingReader{r: req.Body}
+	req.Body = io.NopCloser(body)
+
+	_, err := codec.DecodeBody(req)
+	var se StatusError
+	if !errors.As(err, &se) || se.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a 413 status error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `file "avatar.txt" exceeds 4 bytes`) {
+		t.Fatalf("unexpected error %q", err)
+	}
+	if body.n >= total/2 {
+		t.Fatalf("expected reading to stop early, read %d of %d bytes", body.n, total)
+	}
+
+	req = multipartRequest(t, nil, map[string][]string{"avatar": {"tiny"}})
+	got, err := codec.DecodeBody(req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	defer removeMultipartFiles(req)
+	if got.Avatar == nil || got.Avatar.Size != 4 {
+		t.Fatalf("unexpected avatar: %+v", got.Avatar)
+	}
+
+	codec.MaxBytes = 64
+	req = multipartRequest(t, map[string]string{"title": strings.Repeat("t", 128)}, nil)
+	if _, err := codec.DecodeBody(req); !errors.As(err, &se) || se.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected MaxBytes to still apply, got %v", err)
+	}
+}
+
+func TestRouterGenTS_EmitsFormDataMethod(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, formUpload) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/uploads"), WithCodec[formUpload, struct{}](FormCodec[formUpload, struct{}]{}))
+
+	outDir := t.TempDir()
+	if err := r.GenTSDir(outDir, TSGenOptions{PackageName: "httprpc-test"}); err != nil {
+		t.Fatalf("GenTSDir error: %v", err)
+	}
+	mod, err := os.ReadFile(filepath.Clean(filepath.Join(outDir, "uploads.ts")))
+	if err != nil {
+		t.Fatalf("read uploads.ts: %v", err)
+	}
+	for _, want := range []string{
+		"import { request, toFormData } from './base'",
+		"  avatar: Blob\n",
+		"  attachments: Blob[]\n",
+		"      toFormData(req),\n      { 'Accept': \"application/json\" },",
+	} {
+		if !strings.Contains(string(mod), want) {
+			t.Fatalf("expected %q in generated module:\n%s", want, mod)
+		}
+	}
+	if strings.Contains(string(mod), "interface File") {
+		t.Fatalf("File must not be emitted as an interface:\n%s", mod)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -51,24 +52,46 @@ type tsEndpointModel struct {
 	ResType         string
 	Consumes        string
 	Produces        string
+	ContentTypes    string // documents every content type when there is a choice
 	HasBody         bool
+	IsForm          bool // body is sent as multipart FormData
 	HasParams       bool
-	ParamSegments   []string
-	HeaderFields    []tsHeaderField
+	ParamSegments   []tsPathParam
+	ParamsRequired  bool
+	HeaderFields    []tsMetaField
 	HeadersRequired bool
+	QueryFields     []tsMetaField
+	QueryRequired   bool
+	CookieFields    []tsMetaField
+	CookiesRequired bool
+	Stream          StreamKind
 }
 
-type tsHeaderField struct {
+type tsPathParam struct {
+	Name     string
+	Type     string
+	Optional bool
+}
+
+type tsMetaField struct {
 	Key      string
 	Type     string
 	Optional bool
 }
 
 type tsModel struct {
-	PackageName string
-	ClientName  string
-	Endpoints   []tsEndpointModel
-	TypeDefs    []string
+	PackageName  string
+	ClientName   string
+	Endpoints    []tsEndpointModel
+	TypeDefs     []string
+	HasSSE       bool     // some endpoint streams server-sent events
+	HasNDJSON    bool     // some endpoint streams newline-delimited JSON
+	HasWebSocket bool     // some endpoint upgrades to a WebSocket
+	ErrorCodes   []string // MapError codes, for the ErrorCode union
+
+	// TypeImports and RuntimeImports are what a module file imports from base.ts.
+	TypeImports    []string
+	RuntimeImports []string
 }
 
 //go:embed templates/ts/client.tmpl
@@ -91,64 +114,24 @@ var tsIndexTemplate string
 //
 // where ./cmd/gen constructs your router and calls router.GenTS(...).
 func (r *Router) GenTS(w io.Writer, opts TSGenOptions) error {
+	if err := r.registrationErr(); err != nil {
+		return err
+	}
 	opts = opts.withDefaults()
-	meta := r.Metas
+	meta := r.liveMetas()
 
 	types := collectTypes(meta)
 	typeNames := assignTypeNames(types)
 
-	orderedTypes := orderedByName(typeNames)
-	typeDefs := make([]string, 0, len(orderedTypes))
-	for _, t := range orderedTypes {
-		name := typeNames[t]
-		def, err := tsTypeDef(t, name, typeNames)
-		if err != nil {
-			return err
-		}
-		if def != "" {
-			typeDefs = append(typeDefs, def)
-		}
+	typeDefs, err := tsTypeDefs(typeNames)
+	if err != nil {
+		return err
 	}
 
-	endpoints := make([]tsEndpointModel, 0, len(meta))
-	for _, m := range meta {
-		if m == nil {
-			continue
-		}
-		reqType := typeNames[deref(m.Req)]
-		resType := typeNames[deref(m.Res)]
-		hasBody := endpointHasBody(m.Method, m.Req)
-		hasParams := endpointHasParams(m.Req)
-
-		segments, err := pathParamSegments(m.Path)
-		if err != nil {
-			return err
-		}
-		headerFields, headersRequired, err := metaHeaderFields(m.Meta, typeNames)
-		if err != nil {
-			return err
-		}
-		endpoints = append(endpoints, tsEndpointModel{
-			Method:          strings.ToUpper(m.Method),
-			Path:            m.Path,
-			MethodName:      endpointMethodName(m.Method, m.Path),
-			ReqType:         reqType,
-			ResType:         resType,
-			Consumes:        firstOr(m.Consumes),
-			Produces:        firstOr(m.Produces),
-			HasBody:         hasBody,
-			HasParams:       hasParams,
-			ParamSegments:   segments,
-			HeaderFields:    headerFields,
-			HeadersRequired: headersRequired,
-		})
+	endpoints, err := tsEndpoints(meta, typeNames)
+	if err != nil {
+		return err
 	}
-	sort.SliceStable(endpoints, func(i, j int) bool {
-		if endpoints[i].Path == endpoints[j].Path {
-			return endpoints[i].Method < endpoints[j].Method
-		}
-		return endpoints[i].Path < endpoints[j].Path
-	})
 
 	tmpl, err := template.New("ts").
 		Funcs(template.FuncMap{"quote": strconv.Quote}).
@@ -158,10 +141,14 @@ func (r *Router) GenTS(w io.Writer, opts TSGenOptions) error {
 	}
 
 	model := tsModel{
-		PackageName: opts.PackageName,
-		ClientName:  opts.ClientName,
-		Endpoints:   endpoints,
-		TypeDefs:    typeDefs,
+		PackageName:  opts.PackageName,
+		ClientName:   opts.ClientName,
+		Endpoints:    endpoints,
+		TypeDefs:     typeDefs,
+		HasSSE:       slices.ContainsFunc(endpoints, func(e tsEndpointModel) bool { return e.Stream == StreamSSE }),
+		HasNDJSON:    slices.ContainsFunc(endpoints, func(e tsEndpointModel) bool { return e.Stream == StreamNDJSON }),
+		HasWebSocket: slices.ContainsFunc(endpoints, func(e tsEndpointModel) bool { return e.Stream == StreamWebSocket }),
+		ErrorCodes:   r.errorCodes(),
 	}
 
 	var buf bytes.Buffer
@@ -177,6 +164,9 @@ func (r *Router) GenTS(w io.Writer, opts TSGenOptions) error {
 // GenTSDir writes a multi-file TypeScript client into dir, split by path segment.
 // It overwrites the generated files it creates.
 func (r *Router) GenTSDir(dir string, opts TSGenOptions) error {
+	if err := r.registrationErr(); err != nil {
+		return err
+	}
 	opts = opts.withDefaults()
 	if err := os.MkdirAll(dir, dirPerm); err != nil {
 		return fmt.Errorf("create directory: %w", err)
@@ -184,7 +174,7 @@ func (r *Router) GenTSDir(dir string, opts TSGenOptions) error {
 
 	// Group endpoints by module segment.
 	modules := map[string][]*EndpointMeta{}
-	for _, m := range r.Metas {
+	for _, m := range r.liveMetas() {
 		if m == nil {
 			continue
 		}
@@ -202,7 +192,10 @@ func (r *Router) GenTSDir(dir string, opts TSGenOptions) error {
 	if err != nil {
 		return fmt.Errorf("parse base template: %w", err)
 	}
-	moduleTmpl, err := template.New("module").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(tsModuleTemplate)
+	moduleTmpl, err := template.New("module").Funcs(template.FuncMap{
+		"quote": strconv.Quote,
+		"join":  func(names []string) string { return strings.Join(names, ", ") },
+	}).Parse(tsModuleTemplate)
 	if err != nil {
 		return fmt.Errorf("parse module template: %w", err)
 	}
@@ -215,6 +208,7 @@ func (r *Router) GenTSDir(dir string, opts TSGenOptions) error {
 	if err := writeTemplate(filepath.Join(dir, "base.ts"), baseTmpl, tsModel{
 		PackageName: opts.PackageName,
 		ClientName:  opts.ClientName,
+		ErrorCodes:  r.errorCodes(),
 	}); err != nil {
 		return err
 	}
@@ -232,55 +226,15 @@ func (r *Router) GenTSDir(dir string, opts TSGenOptions) error {
 		metas := modules[key]
 		types := collectTypes(metas)
 		typeNames := assignTypeNames(types)
-		orderedTypes := orderedByName(typeNames)
-
-		typeDefs := make([]string, 0, len(orderedTypes))
-		for _, t := range orderedTypes {
-			name := typeNames[t]
-			def, err := tsTypeDef(t, name, typeNames)
-			if err != nil {
-				return err
-			}
-			if def != "" {
-				typeDefs = append(typeDefs, def)
-			}
+		typeDefs, err := tsTypeDefs(typeNames)
+		if err != nil {
+			return err
 		}
 
-		endpoints := make([]tsEndpointModel, 0, len(metas))
-		for _, m := range metas {
-			reqType := typeNames[deref(m.Req)]
-			resType := typeNames[deref(m.Res)]
-			hasBody := endpointHasBody(m.Method, m.Req)
-			hasParams := endpointHasParams(m.Req)
-			segments, err := pathParamSegments(m.Path)
-			if err != nil {
-				return err
-			}
-			headerFields, headersRequired, err := metaHeaderFields(m.Meta, typeNames)
-			if err != nil {
-				return err
-			}
-			endpoints = append(endpoints, tsEndpointModel{
-				Method:          strings.ToUpper(m.Method),
-				Path:            m.Path,
-				MethodName:      endpointMethodName(m.Method, m.Path),
-				ReqType:         reqType,
-				ResType:         resType,
-				Consumes:        firstOr(m.Consumes),
-				Produces:        firstOr(m.Produces),
-				HasBody:         hasBody,
-				HasParams:       hasParams,
-				ParamSegments:   segments,
-				HeaderFields:    headerFields,
-				HeadersRequired: headersRequired,
-			})
-		}
-		sort.SliceStable(endpoints, func(i, j int) bool {
-			if endpoints[i].Path == endpoints[j].Path {
-				return endpoints[i].Method < endpoints[j].Method
-			}
-			return endpoints[i].Path < endpoints[j].Path
-		})
+		endpoints, err := tsEndpoints(metas, typeNames)
+		if err != nil {
+			return err
+		}
 
 		model := tsModel{
 			PackageName: opts.PackageName,
@@ -288,6 +242,7 @@ func (r *Router) GenTSDir(dir string, opts TSGenOptions) error {
 			Endpoints:   endpoints,
 			TypeDefs:    typeDefs,
 		}
+		model.TypeImports, model.RuntimeImports = moduleImports(endpoints)
 
 		file := moduleFileName(key) + ".ts"
 		if err := writeTemplate(filepath.Join(dir, file), moduleTmpl, model); err != nil {
@@ -318,6 +273,135 @@ func (r *Router) GenTSDir(dir string, opts TSGenOptions) error {
 	return nil
 }
 
+func tsTypeDefs(typeNames map[reflect.Type]string) ([]string, error) {
+	orderedTypes := orderedByName(typeNames)
+	typeDefs := make([]string, 0, len(orderedTypes))
+	for _, t := range orderedTypes {
+		def, err := tsTypeDef(t, typeNames[t], typeNames)
+		if err != nil {
+			return nil, err
+		}
+		if def != "" {
+			typeDefs = append(typeDefs, def)
+		}
+	}
+	return typeDefs, nil
+}
+
+func tsEndpoints(metas []*EndpointMeta, typeNames map[reflect.Type]string) ([]tsEndpointModel, error) {
+	endpoints := make([]tsEndpointModel, 0, len(metas))
+	for _, m := range metas {
+		if m == nil {
+			continue
+		}
+		// The generated Socket exchanges JSON text messages only.
+		if m.Stream == StreamWebSocket && (!isJSONContentType(firstOr(m.Consumes)) || !isJSONContentType(firstOr(m.Produces))) {
+			return nil, fmt.Errorf("websocket %s: generated clients only support JSON messages, got %s in and %s out",
+				m.Path, firstOr(m.Consumes), firstOr(m.Produces))
+		}
+		segments, paramsRequired, err := pathParamSegments(m.Path)
+		if err != nil {
+			return nil, err
+		}
+		headerFields, headersRequired, err := metaTSFields(m.Meta, "header", typeNames)
+		if err != nil {
+			return nil, err
+		}
+		queryFields, queryRequired, err := metaTSFields(m.Meta, "query", typeNames)
+		if err != nil {
+			return nil, err
+		}
+		cookieFields, cookiesRequired, err := metaTSFields(m.Meta, "cookie", typeNames)
+		if err != nil {
+			return nil, err
+		}
+		// TypeScript only allows optional parameters at the end, so once a
+		// group is optional every group after it is emitted as optional too.
+		optional := len(segments) > 0 && !paramsRequired
+		for _, g := range []struct {
+			present  bool
+			required *bool
+		}{
+			{len(headerFields) > 0, &headersRequired},
+			{len(queryFields) > 0, &queryRequired},
+			{len(cookieFields) > 0, &cookiesRequired},
+		} {
+			if !g.present {
+				continue
+			}
+			if optional {
+				*g.required = false
+			}
+			optional = !*g.required
+		}
+
+		endpoints = append(endpoints, tsEndpointModel{
+			Method:          strings.ToUpper(m.Method),
+			Path:            m.Path,
+			MethodName:      endpointMethodName(m.Method, m.Path),
+			ReqType:         typeNames[deref(m.Req)],
+			ResType:         typeNames[deref(m.Res)],
+			Consumes:        preferJSON(m.Consumes),
+			Produces:        preferJSON(m.Produces),
+			ContentTypes:    contentTypesDoc(m.Consumes, m.Produces),
+			HasBody:         endpointHasBody(m.Method, m.Req),
+			IsForm:          endpointHasBody(m.Method, m.Req) && preferJSON(m.Consumes) == "multipart/form-data",
+			HasParams:       endpointHasParams(m.Req),
+			ParamSegments:   segments,
+			ParamsRequired:  paramsRequired,
+			HeaderFields:    headerFields,
+			HeadersRequired: headersRequired,
+			QueryFields:     queryFields,
+			QueryRequired:   queryRequired,
+			CookieFields:    cookieFields,
+			CookiesRequired: cookiesRequired,
+			Stream:          m.Stream,
+		})
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Path == endpoints[j].Path {
+			return endpoints[i].Method < endpoints[j].Method
+		}
+		return endpoints[i].Path < endpoints[j].Path
+	})
+	return endpoints, nil
+}
+
+// moduleImports returns the base.ts types and functions the endpoints of a
+// module file use.
+func moduleImports(endpoints []tsEndpointModel) (types, funcs []string) {
+	types = []string{"ClientOptions"}
+	var request, form, sse, ndjson, socket bool
+	for _, e := range endpoints {
+		switch e.Stream {
+		case StreamSSE:
+			sse = true
+		case StreamNDJSON:
+			ndjson = true
+		case StreamWebSocket:
+			socket = true
+		default:
+			request = true
+			form = form || e.IsForm
+		}
+	}
+	if sse || ndjson {
+		types = append(types, "StreamOptions")
+	}
+	if socket {
+		types = append(types, "Socket")
+	}
+	for _, f := range []struct {
+		name string
+		used bool
+	}{{"ndjson", ndjson}, {"request", request}, {"socket", socket}, {"sse", sse}, {"toFormData", form}} {
+		if f.used {
+			funcs = append(funcs, f.name)
+		}
+	}
+	return types, funcs
+}
+
 func firstOr(in []string) string {
 	if len(in) == 0 || in[0] == "" {
 		return "application/json"
@@ -325,6 +409,22 @@ func firstOr(in []string) string {
 	return in[0]
 }
 
+// preferJSON returns application/json if it is offered, since the generated
+// client speaks JSON, and the first content type otherwise.
+func preferJSON(in []string) string {
+	if slices.Contains(in, "application/json") {
+		return "application/json"
+	}
+	return firstOr(in)
+}
+
+func contentTypesDoc(consumes, produces []string) string {
+	if len(consumes) <= 1 && len(produces) <= 1 {
+		return ""
+	}
+	return "Consumes: " + strings.Join(consumes, ", ") + ". Produces: " + strings.Join(produces, ", ") + "."
+}
+
 func moduleKey(path string, skip int) string {
 	path = strings.Trim(path, "/")
 	if path == "" {
@@ -395,7 +495,7 @@ func collectTypes(metas []*EndpointMeta) []reflect.Type {
 		if t.Kind() == reflect.Pointer {
 			return
 		}
-		if seen[t] {
+		if seen[t] || t == fileType {
 			return
 		}
 		seen[t] = true
@@ -405,7 +505,7 @@ func collectTypes(metas []*EndpointMeta) []reflect.Type {
 			out = append(out, t)
 			for i := range t.NumField() {
 				f := t.Field(i)
-				if !f.IsExported() {
+				if !f.IsExported() && !isPromotedJSONEmbed(f) {
 					continue
 				}
 				visit(f.Type)
@@ -429,9 +529,15 @@ func collectTypes(metas []*EndpointMeta) []reflect.Type {
 	return out
 }
 
+// tsRuntimeNames are declared by the generated runtime code.
+var tsRuntimeNames = []string{"ApiError", "ClientOptions", "ErrorCode", "HttpMethod", "ProblemDetails", "Socket", "StreamEvent", "StreamOptions", "Violation"}
+
 func assignTypeNames(types []reflect.Type) map[reflect.Type]string {
 	out := map[reflect.Type]string{}
 	used := map[string]int{}
+	for _, name := range tsRuntimeNames {
+		used[name] = 1 // generated types named like these get a numeric suffix
+	}
 
 	for _, t := range types {
 		base := t.Name()
@@ -472,7 +578,7 @@ func orderedByName(typeNames map[reflect.Type]string) []reflect.Type {
 }
 
 func endpointMethodName(method, path string) string {
-	name := strings.ToLower(method) + "_" + strings.Trim(path, "/")
+	name := strings.ToLower(method) + "_" + strings.Trim(stripParamConstraints(path), "/")
 	name = strings.ReplaceAll(name, "/", "_")
 	name = strings.ReplaceAll(name, "-", "_")
 	name = strings.ReplaceAll(name, "{", "")
@@ -485,18 +591,35 @@ func endpointMethodName(method, path string) string {
 	return name
 }
 
-func pathParamSegments(path string) ([]string, error) {
+func pathParamSegments(path string) ([]tsPathParam, bool, error) {
 	pattern, err := parseRoutePattern(path)
 	if err != nil {
-		return nil, fmt.Errorf("parse path %q: %w", path, err)
+		return nil, false, fmt.Errorf("parse path %q: %w", path, err)
 	}
 	if len(pattern.params) == 0 {
-		return nil, nil
+		return nil, false, nil
 	}
-	return append([]string(nil), pattern.params...), nil
+	out := make([]tsPathParam, 0, len(pattern.params))
+	required := false
+	for _, seg := range pattern.segments {
+		switch seg.kind {
+		case segmentParam:
+			out = append(out, tsPathParam{Name: seg.value, Type: seg.constraint.tsType(), Optional: seg.optional})
+			if !seg.optional {
+				required = true
+			}
+		case segmentCatchAll:
+			out = append(out, tsPathParam{Name: seg.value, Type: "string", Optional: true})
+		default:
+			// static segments are part of the path literal
+		}
+	}
+	return out, required, nil
 }
 
-func metaHeaderFields(meta reflect.Type, typeNames map[reflect.Type]string) ([]tsHeaderField, bool, error) {
+// metaTSFields returns the meta fields bound with the given tag key ("header",
+// "query" or "cookie") and whether any of them is required.
+func metaTSFields(meta reflect.Type, key string, typeNames map[reflect.Type]string) ([]tsMetaField, bool, error) {
 	meta = deref(meta)
 	if meta == nil {
 		return nil, false, nil
@@ -505,7 +628,7 @@ func metaHeaderFields(meta reflect.Type, typeNames map[reflect.Type]string) ([]t
 		return nil, false, fmt.Errorf("meta type %s must be a struct", meta.Kind())
 	}
 
-	var fields []tsHeaderField
+	var fields []tsMetaField
 	required := false
 	for i := range meta.NumField() {
 		field := meta.Field(i)
@@ -516,7 +639,7 @@ func metaHeaderFields(meta reflect.Type, typeNames map[reflect.Type]string) ([]t
 			continue
 		}
 
-		tag, err := parseMetaTag(meta, field, "header", false)
+		tag, err := parseMetaTag(meta, field, key, key == "query")
 		if err != nil {
 			return nil, false, err
 		}
@@ -524,12 +647,22 @@ func metaHeaderFields(meta reflect.Type, typeNames map[reflect.Type]string) ([]t
 			continue
 		}
 
-		fields = append(fields, tsHeaderField{
+		rules, err := parseFieldRules(meta, field)
+		if err != nil {
+			return nil, false, err
+		}
+		tsType := tsTypeExpr(field.Type, typeNames)
+		if rules.enum != nil {
+			tsType = tsEnumTypeExpr(field.Type, rules.enum)
+		}
+		// A missing value with a default is filled in by the server.
+		optional := tag.omitempty || rules.hasDefault()
+		fields = append(fields, tsMetaField{
 			Key:      tsObjectKey(tag.name),
-			Type:     tsTypeExpr(field.Type, typeNames),
-			Optional: tag.omitempty,
+			Type:     tsType,
+			Optional: optional,
 		})
-		if !tag.omitempty {
+		if !optional {
 			required = true
 		}
 	}
@@ -600,18 +733,20 @@ func tsTypeDef(t reflect.Type, name string, typeNames map[reflect.Type]string) (
 		return fmt.Sprintf("export type %s = Record<string, never>", name), nil
 	}
 
-	var b strings.Builder
-	b.WriteString("export interface ")
-	b.WriteString(name)
-	b.WriteString(" {\n")
-
+	var (
+		body    strings.Builder
+		extends []string
+	)
 	for i := range t.NumField() {
 		f := t.Field(i)
-		if !f.IsExported() {
+		if isPromotedJSONEmbed(f) {
+			// Embedded fields are flattened by encoding/json; mirror that with extends.
+			if embedded, ok := typeNames[deref(f.Type)]; ok {
+				extends = append(extends, embedded)
+			}
 			continue
 		}
-		if f.Anonymous && deref(f.Type).Kind() == reflect.Struct {
-			// Skip embedding for now; generator can be improved to merge fields.
+		if !f.IsExported() {
 			continue
 		}
 
@@ -623,22 +758,64 @@ func tsTypeDef(t reflect.Type, name string, typeNames map[reflect.Type]string) (
 			continue
 		}
 
+		rules, err := parseFieldRules(t, f)
+		if err != nil {
+			return "", err
+		}
 		tsType := tsTypeExpr(f.Type, typeNames)
+		if rules.enum != nil {
+			tsType = tsEnumTypeExpr(f.Type, rules.enum)
+		}
+		docs, err := tsFieldDocs(t, f, rules)
+		if err != nil {
+			return "", err
+		}
+		if len(docs) == 1 {
+			body.WriteString("  /** " + docs[0] + " */\n")
+		} else if len(docs) > 1 {
+			body.WriteString("  /**\n")
+			for _, d := range docs {
+				body.WriteString("   * " + d + "\n")
+			}
+			body.WriteString("   */\n")
+		}
 		optional := omit
-		b.WriteString("  ")
-		b.WriteString(jsonName)
+		body.WriteString("  ")
+		body.WriteString(jsonName)
 		if optional {
-			b.WriteString("?")
+			body.WriteString("?")
 		}
-		b.WriteString(": ")
-		b.WriteString(tsType)
-		b.WriteString("\n")
+		body.WriteString(": ")
+		body.WriteString(tsType)
+		body.WriteString("\n")
 	}
 
+	var b strings.Builder
+	b.WriteString("export interface ")
+	b.WriteString(name)
+	if len(extends) > 0 {
+		b.WriteString(" extends ")
+		b.WriteString(strings.Join(extends, ", "))
+	}
+	b.WriteString(" {\n")
+	b.WriteString(body.String())
 	b.WriteString("}")
 	return b.String(), nil
 }
 
+// isPromotedJSONEmbed reports whether encoding/json promotes the fields of the
+// embedded struct f into its parent (no explicit json name, reachable).
+func isPromotedJSONEmbed(f reflect.StructField) bool {
+	if !f.Anonymous || deref(f.Type).Kind() != reflect.Struct {
+		return false
+	}
+	if !f.IsExported() && f.Type.Kind() == reflect.Pointer {
+		return false
+	}
+	name, found, skip := tagName(f, "json")
+	return !skip && (!found || name == "")
+}
+
 func hasJSONBody(req reflect.Type) bool {
 	req = deref(req)
 	if req.Kind() == reflect.Struct && req.NumField() == 0 {
@@ -669,6 +846,9 @@ func tsTypeExpr(t reflect.Type, typeNames map[reflect.Type]string) string {
 	if t == nil {
 		return unknownType
 	}
+	if t.Kind() == reflect.Pointer && t.Elem() == fileType {
+		return "Blob"
+	}
 	switch t.Kind() {
 	case reflect.Pointer:
 		return tsTypeExpr(t.Elem(), typeNames) + " | null"
@@ -700,6 +880,75 @@ func tsTypeExpr(t reflect.Type, typeNames map[reflect.Type]string) string {
 	}
 }
 
+// tsFieldDocs returns the JSDoc tags describing f's default and validate
+// rules, using the JSON Schema keyword names.
+func tsFieldDocs(owner reflect.Type, f reflect.StructField, rules fieldRules) ([]string, error) {
+	var docs []string
+	if rules.hasDefault() {
+		docs = append(docs, "@default "+strings.Join(rules.def, ","))
+	}
+	validate, err := parseValidateTag(owner, f)
+	if err != nil {
+		return nil, err
+	}
+	kind := deref(f.Type).Kind()
+	bound := func(lower bool) string {
+		suffix := "imum"
+		switch kind {
+		case reflect.String:
+			suffix = "Length"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			suffix = "Items"
+		default:
+		}
+		if lower {
+			return "@min" + suffix
+		}
+		return "@max" + suffix
+	}
+	for _, r := range validate {
+		switch r.code {
+		case "min":
+			docs = append(docs, bound(true)+" "+r.arg)
+		case "max":
+			docs = append(docs, bound(false)+" "+r.arg)
+		case "len":
+			docs = append(docs, bound(true)+" "+r.arg, bound(false)+" "+r.arg)
+		case "email":
+			docs = append(docs, "@format email")
+		case "url":
+			docs = append(docs, "@format uri")
+		}
+	}
+	return docs, nil
+}
+
+// tsEnumTypeExpr is tsTypeExpr with the scalar type replaced by a union of
+// the enum values.
+func tsEnumTypeExpr(t reflect.Type, enum []string) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return tsEnumTypeExpr(t.Elem(), enum) + " | null"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return "(" + tsEnumTypeExpr(t.Elem(), enum) + ")[]"
+		}
+	default:
+	}
+	literals := make([]string, len(enum))
+	for i, v := range enum {
+		switch t.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			literals[i] = v
+		default:
+			literals[i] = strconv.Quote(v)
+		}
+	}
+	return strings.Join(literals, " | ")
+}
+
 func requiredSnakeCaseJSONFieldName(owner reflect.Type, f reflect.StructField) (name string, omitempty, skip bool, err error) {
 	tag, ok := f.Tag.Lookup("json")
 	if !ok {
@@ -1,6 +1,7 @@
 package httprpc
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
@@ -116,3 +117,155 @@ func TestRouterGenTS_EmitsHeaderParams(t *testing.T) {
 		t.Fatalf("expected headers signature in generated client")
 	}
 }
+
+func TestRouterGenTS_EmitsQueryAndCookieParams(t *testing.T) {
+	type deployReq struct {
+		Service string `json:"service"`
+	}
+	type deployMeta struct {
+		Session string `cookie:"session_id"`
+		DryRun  bool   `query:"dry_run,omitempty"`
+		IP      string `remote:"ip"`
+	}
+
+	r := New()
+	RegisterHandlerM(r.EndpointGroup, POSTM(func(context.Context, deployReq, deployMeta) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/deploys"))
+
+	outDir := t.TempDir()
+	if err := r.GenTSDir(outDir, TSGenOptions{PackageName: "httprpc-test", ClientName: "API"}); err != nil {
+		t.Fatalf("GenTSDir error: %v", err)
+	}
+
+	mod, err := os.ReadFile(filepath.Clean(filepath.Join(outDir, "deploys.ts")))
+	if err != nil {
+		t.Fatalf("read deploys.ts: %v", err)
+	}
+	for _, want := range []string{
+		"query?: {dry_run?: boolean }",
+		"cookies?: {session_id: string }",
+		"      query,\n      undefined,\n      cookies,",
+	} {
+		if !strings.Contains(string(mod), want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, mod)
+		}
+	}
+	if strings.Contains(string(mod), "ip") && strings.Contains(string(mod), "remote") {
+		t.Fatalf("remote fields must not appear in the generated client")
+	}
+}
+
+func TestRouterGenTS_EmitsEnumsAndDefaults(t *testing.T) {
+	type listReq struct {
+		Sort   string   `json:"sort" default:"asc" enum:"asc,desc"`
+		Limit  int      `json:"limit" enum:"10,20"`
+		States []string `json:"states" enum:"open,closed"`
+	}
+	type listMeta struct {
+		Format string `query:"format" default:"json" enum:"json,csv"`
+	}
+
+	r := New()
+	RegisterHandlerM(r.EndpointGroup, GETM(func(context.Context, listReq, listMeta) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/items"))
+
+	var buf bytes.Buffer
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"  /** @default asc */\n  sort: \"asc\" | \"desc\"\n",
+		"  limit: 10 | 20\n",
+		"  states: (\"open\" | \"closed\")[]\n",
+		"query?: {format?: \"json\" | \"csv\" }",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, out)
+		}
+	}
+}
+
+func TestRouterGenTS_EmitsCatchAllAndOptionalParams(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (pingRes, error) {
+		return pingRes{}, nil
+	}, "/files/*path"))
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (pingRes, error) {
+		return pingRes{}, nil
+	}, "/docs/:section?"))
+
+	outDir := t.TempDir()
+	if err := r.GenTSDir(outDir, TSGenOptions{PackageName: "httprpc-test", ClientName: "API"}); err != nil {
+		t.Fatalf("GenTSDir error: %v", err)
+	}
+
+	files, err := os.ReadFile(filepath.Clean(filepath.Join(outDir, "files.ts")))
+	if err != nil {
+		t.Fatalf("read files.ts: %v", err)
+	}
+	if !strings.Contains(string(files), "params?: {path?: string }") {
+		t.Fatalf("expected catch-all params signature in generated client:\n%s", files)
+	}
+	docs, err := os.ReadFile(filepath.Clean(filepath.Join(outDir, "docs.ts")))
+	if err != nil {
+		t.Fatalf("read docs.ts: %v", err)
+	}
+	if !strings.Contains(string(docs), "params?: {section?: string | number }") {
+		t.Fatalf("expected optional params signature in generated client:\n%s", docs)
+	}
+}
+
+func TestRouterGenTS_NarrowsConstrainedParams(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (pingRes, error) {
+		return pingRes{}, nil
+	}, "/users/:id<int>/keys/:key<uuid>"))
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{PackageName: "httprpc-test", ClientName: "API"}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "params: {id: number, key: string }") {
+		t.Fatalf("expected constrained params signature in generated client:\n%s", out)
+	}
+	if !strings.Contains(out, "async get_users_id_keys_key(") {
+		t.Fatalf("expected constraint-free method name in generated client:\n%s", out)
+	}
+}
+
+type listPagination struct {
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+}
+
+type listUsersReq struct {
+	listPagination
+	Filter map[string]string `json:"filter"`
+}
+
+func TestRouterGenTS_EmbeddedStructsExtend(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, listUsersReq) (pingRes, error) {
+		return pingRes{}, nil
+	}, "/users"))
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{PackageName: "httprpc-test", ClientName: "API"}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"export interface listPagination {",
+		"export interface listUsersReq extends listPagination {",
+		"filter: Record<string, string>",
+		"appendQuery(searchParams, `${key}[${k}]`, v)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, out)
+		}
+	}
+}
@@ -3,40 +3,88 @@ package httprpc
 import (
 	"bytes"
 	"context"
-	"log/slog"
+	"errors"
 	"strings"
 	"testing"
 )
 
-func TestRegisterAfterHandlerLogs(t *testing.T) {
+func TestRegisterAfterHandlerReturnsError(t *testing.T) {
 	r := New()
 	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
 		return struct{}{}, nil
 	}, "/early"))
 
-	// Capture log output
-	var buf bytes.Buffer
-	prev := slog.Default()
-	logger := slog.New(slog.NewTextHandler(&buf, nil))
-	slog.SetDefault(logger)
-	defer slog.SetDefault(prev)
-
-	_, _ = r.Handler()
+	if _, err := r.Handler(); err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
 
-	// Try to register after building handler (should log error instead of panic)
+	// Registering after the handler is built is recorded instead of silently dropped.
 	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
 		return struct{}{}, nil
 	}, "/late"))
 
-	// Check that error was logged
-	logOutput := buf.String()
-	if !strings.Contains(logOutput, "cannot register handlers after handler is built") {
-		t.Errorf("expected error log about registration after handler build, got: %s", logOutput)
+	_, err := r.Handler()
+	if !errors.Is(err, errSealed) {
+		t.Fatalf("expected sealed error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "GET /late") {
+		t.Fatalf("expected method and path in error, got %v", err)
+	}
+	if err := r.GenTS(&bytes.Buffer{}, TSGenOptions{}); !errors.Is(err, errSealed) {
+		t.Fatalf("expected GenTS to return sealed error, got %v", err)
+	}
+	if err := r.GenTSDir(t.TempDir(), TSGenOptions{}); !errors.Is(err, errSealed) {
+		t.Fatalf("expected GenTSDir to return sealed error, got %v", err)
+	}
+}
+
+func TestRegistrationErrorsAreCollected(t *testing.T) {
+	type badTag struct {
+		ID int `path:"idd"`
+	}
+
+	r := New()
+	RegisterHandlerM(r.EndpointGroup, GETM(func(context.Context, struct{}, badTag) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/users/:id"))
+	RegisterHandlerM(r.EndpointGroup, GETM(func(context.Context, struct{}, *badTag) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/items/:id"))
+	r.Host("bad..example.com")
+
+	_, err := r.Handler()
+	if err == nil {
+		t.Fatalf("expected registration errors")
 	}
-	if !strings.Contains(logOutput, "method=GET") {
-		t.Errorf("expected method in log output, got: %s", logOutput)
+	for _, want := range []string{
+		`register GET /users/:id`,
+		`path tag "idd" does not match route`,
+		`register GET /items/:id`,
+		`must be a struct (non-pointer)`,
+		`invalid host pattern "bad..example.com"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to contain %q, got %v", want, err)
+		}
 	}
-	if !strings.Contains(logOutput, "path=/late") {
-		t.Errorf("expected path in log output, got: %s", logOutput)
+	if len(r.Metas) != 0 {
+		t.Fatalf("expected invalid endpoints not to be registered, got %d metas", len(r.Metas))
 	}
 }
+
+func TestStrictRegistrationPanics(t *testing.T) {
+	r := New(WithStrictRegistration())
+
+	defer func() {
+		rec := recover()
+		err, ok := rec.(error)
+		if !ok || !strings.Contains(err.Error(), `path tag "idd" does not match route`) {
+			t.Fatalf("expected registration panic, got %v", rec)
+		}
+	}()
+	RegisterHandlerM(r.EndpointGroup, GETM(func(context.Context, struct{}, struct {
+		ID int `path:"idd"`
+	}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/users/:id"))
+}
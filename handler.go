@@ -2,8 +2,11 @@ package httprpc
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"net/netip"
+	"slices"
 )
 
 type pathParamsKey struct{}
@@ -16,47 +19,84 @@ type HandlerWithMeta[Req, Meta, Res any] func(ctx context.Context, request Req,
 
 // PathParam returns a path parameter by name.
 func PathParam(ctx context.Context, name string) (string, bool) {
-	values, _ := ctx.Value(pathParamsKey{}).(map[string]string)
-	if values == nil {
-		return "", false
-	}
-	val, ok := values[name]
-	return val, ok
+	values, _ := ctx.Value(pathParamsKey{}).(*pathParams)
+	return values.get(name)
 }
 
 // PathParams returns a copy of all path params.
 func PathParams(ctx context.Context) map[string]string {
-	values, _ := ctx.Value(pathParamsKey{}).(map[string]string)
-	if len(values) == 0 {
+	values, _ := ctx.Value(pathParamsKey{}).(*pathParams)
+	n := values.len()
+	if n == 0 {
 		return nil
 	}
-	out := make(map[string]string, len(values))
-	for k, v := range values {
-		out[k] = v
+	out := make(map[string]string, n)
+	for i := range n {
+		out[values.names[i]] = values.values[i]
 	}
 	return out
 }
 
-func withPathParams(r *http.Request, params map[string]string) *http.Request {
-	if len(params) == 0 {
+// withPathParams attaches a copy of params to the request context. params is
+// pooled and reused once the route returns, while the context may outlive the
+// request (goroutines, deferred callbacks), so it gets its own values.
+func withPathParams(r *http.Request, params *pathParams) *http.Request {
+	n := params.len()
+	if n == 0 {
 		return r
 	}
-	return r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params))
+	own := &pathParams{names: params.names[:n:n], values: slices.Clone(params.values[:n])}
+	return r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, own))
+}
+
+// handlerConfig carries the router settings adapted handlers need.
+type handlerConfig struct {
+	trustedProxies []netip.Prefix
+	redactErrors   bool
+	// group is the group the handler was registered on, for MapError.
+	group *EndpointGroup
+}
+
+// handlerError prepares an error returned by a handler for encoding. Errors
+// are first translated by the group's MapError mappings. With redaction on,
+// errors whose message is not meant for clients are then logged and replaced
+// by a bare 500.
+func (c handlerConfig) handlerError(r *http.Request, err error) error {
+	if c.group != nil {
+		err = c.group.mapError(err)
+	}
+	if !c.redactErrors || isPublicError(err) {
+		return err
+	}
+	slog.Error("handler failed", "error", err, "method", r.Method, "path", r.URL.Path)
+	return StatusError{Status: http.StatusInternalServerError}
 }
 
-func adaptHandler[Req, Res any](codec Codec[Req, Res], handler Handler[Req, Res]) http.Handler {
+func adaptHandler[Req, Res any](endpointCodec Codec[Req, Res], handler Handler[Req, Res], cfg handlerConfig) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var (
-			req Req
-			err error
-		)
-		if r.Method == http.MethodGet {
+		defer removeMultipartFiles(r)
+		codec, err := codecForRequest(endpointCodec, r)
+		if err != nil {
+			if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
+				slog.Error("failed to encode error response", "error", encodeErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		var req Req
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
 			req, err = codec.DecodeQuery(r)
 		} else {
 			req, err = codec.DecodeBody(r)
 		}
 		if err != nil {
-			if encodeErr := codec.EncodeError(w, StatusError{Status: http.StatusBadRequest, Err: err}); encodeErr != nil {
+			err = decodeFailure(err)
+		} else if violations := validateValue(&req, false); len(violations) > 0 {
+			err = validationFailure(violations)
+		}
+		if err != nil {
+			if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
 				slog.Error("failed to encode error response", "error", encodeErr)
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			}
@@ -65,6 +105,7 @@ func adaptHandler[Req, Res any](codec Codec[Req, Res], handler Handler[Req, Res]
 
 		res, err := handler(r.Context(), req)
 		if err != nil {
+			err = cfg.handlerError(r, err)
 			if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
 				slog.Error("failed to encode error response", "error", encodeErr)
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -81,23 +122,35 @@ func adaptHandler[Req, Res any](codec Codec[Req, Res], handler Handler[Req, Res]
 	})
 }
 
-func adaptHandlerWithMeta[Req, Meta, Res any](codec Codec[Req, Res], handler HandlerWithMeta[Req, Meta, Res]) http.Handler {
+func adaptHandlerWithMeta[Req, Meta, Res any](endpointCodec Codec[Req, Res], handler HandlerWithMeta[Req, Meta, Res], cfg handlerConfig) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var (
-			req Req
-			err error
-		)
-		if r.Method == http.MethodGet {
+		defer removeMultipartFiles(r)
+		codec, err := codecForRequest(endpointCodec, r)
+		if err != nil {
+			if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
+				slog.Error("failed to encode error response", "error", encodeErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		var req Req
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
 			req, err = codec.DecodeQuery(r)
 		} else {
 			req, err = codec.DecodeBody(r)
 		}
 		var meta Meta
 		if err == nil {
-			meta, err = decodeRequestMeta[Meta](r)
+			meta, err = decodeRequestMeta[Meta](r, cfg.trustedProxies)
+		}
+		if err != nil {
+			err = decodeFailure(err)
+		} else if violations := append(validateValue(&req, false), validateValue(&meta, true)...); len(violations) > 0 {
+			err = validationFailure(violations)
 		}
 		if err != nil {
-			if encodeErr := codec.EncodeError(w, StatusError{Status: http.StatusBadRequest, Err: err}); encodeErr != nil {
+			if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
 				slog.Error("failed to encode error response", "error", encodeErr)
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			}
@@ -106,6 +159,7 @@ func adaptHandlerWithMeta[Req, Meta, Res any](codec Codec[Req, Res], handler Han
 
 		res, err := handler(r.Context(), req, meta)
 		if err != nil {
+			err = cfg.handlerError(r, err)
 			if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
 				slog.Error("failed to encode error response", "error", encodeErr)
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -121,3 +175,13 @@ func adaptHandlerWithMeta[Req, Meta, Res any](codec Codec[Req, Res], handler Han
 		}
 	})
 }
+
+// decodeFailure marks a decode error as 400 Bad Request unless the codec
+// already chose a status (e.g. 413 for oversized bodies).
+func decodeFailure(err error) error {
+	var se StatusError
+	if errors.As(err, &se) && se.Status != 0 {
+		return err
+	}
+	return StatusError{Status: http.StatusBadRequest, Err: err}
+}
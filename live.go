@@ -0,0 +1,102 @@
+package httprpc
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// LiveHandler serves the router's endpoints from a route table that can be
+// rebuilt and swapped at runtime. Unlike Handler, it does not seal the router:
+// endpoints can still be registered, mounted or unregistered, and take effect
+// on the next Reload. In-flight requests finish on the table they started on.
+type LiveHandler struct {
+	router *Router
+
+	reloadMu sync.Mutex
+	table    atomic.Pointer[routeTable]
+}
+
+// LiveHandler builds the router's route table and returns a handler serving it.
+// It returns the errors recorded during registration, or an error if routes are invalid.
+func (r *Router) LiveHandler() (*LiveHandler, error) {
+	h := &LiveHandler{router: r}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Reload rebuilds the route table from the current registrations and swaps it in.
+// Registration errors recorded since the last Reload are returned and cleared
+// (the offending endpoints were never added). On error the previous table keeps serving.
+func (h *LiveHandler) Reload() error {
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	if err := h.router.takeRegistrationErr(); err != nil {
+		return err
+	}
+	table, err := h.router.compile(false)
+	if err != nil {
+		return err
+	}
+	h.table.Store(table)
+	h.router.table.Store(table)
+	return nil
+}
+
+func (h *LiveHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.table.Load().ServeHTTP(w, req)
+}
+
+// Unregister removes the endpoints registered with method and path (as passed
+// to registration, including the group prefix). Pass an empty method to remove
+// a Mount at path. It reports whether anything was removed.
+// It has no effect once the router is sealed by Handler.
+func (r *Router) Unregister(method, path string) bool {
+	path = normalizeRoutePath(path)
+	return r.unregister(func(e *endpoint) bool {
+		return e.Method == method && normalizeRoutePath(e.Path) == path
+	})
+}
+
+// UnregisterName removes the endpoint registered with WithName(name).
+// It reports whether anything was removed.
+// It has no effect once the router is sealed by Handler.
+func (r *Router) UnregisterName(name string) bool {
+	if name == "" {
+		return false
+	}
+	return r.unregister(func(e *endpoint) bool {
+		return e.Meta != nil && e.Meta.Name == name
+	})
+}
+
+func (r *Router) unregister(match func(*endpoint) bool) bool {
+	root := r.rootGroup()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if root.sealed {
+		return false
+	}
+
+	removed := false
+	handlers := root.Handlers[:0:0]
+	metas := root.Metas[:0:0]
+	for _, e := range root.Handlers {
+		if e != nil && match(e) {
+			removed = true
+			continue
+		}
+		handlers = append(handlers, e)
+		if e != nil && e.Meta != nil {
+			metas = append(metas, e.Meta)
+		}
+	}
+	if removed {
+		root.Handlers = handlers
+		root.Metas = metas
+	}
+	return removed
+}
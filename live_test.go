@@ -0,0 +1,244 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func liveStatus(h http.Handler, method, path string) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, http.NoBody))
+	return rec.Code
+}
+
+func TestLiveHandler_ReloadAndUnregister(t *testing.T) {
+	r := New()
+	ok := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	RegisterHandler(r.EndpointGroup, GET(ok, "/core"))
+
+	h, err := r.LiveHandler()
+	if err != nil {
+		t.Fatalf("live handler error: %v", err)
+	}
+
+	plugin := r.Group("/plugins/foo")
+	RegisterHandler(plugin, GET(ok, "/items"), WithName[struct{}, struct{}]("foo.items"))
+	RegisterHandler(plugin, POST(ok, "/items"))
+
+	if got := liveStatus(h, http.MethodGet, "/plugins/foo/items"); got != http.StatusNotFound {
+		t.Fatalf("expected new endpoint to wait for Reload, got %d", got)
+	}
+	if err := h.Reload(); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if got := liveStatus(h, http.MethodGet, "/plugins/foo/items"); got != http.StatusOK {
+		t.Fatalf("expected %d after Reload, got %d", http.StatusOK, got)
+	}
+	if got := len(r.Describe()); got != 3 {
+		t.Fatalf("expected 3 described endpoints, got %d", got)
+	}
+
+	if !r.UnregisterName("foo.items") {
+		t.Fatalf("expected UnregisterName to remove the endpoint")
+	}
+	if !r.Unregister(http.MethodPost, "/plugins/foo/items/") {
+		t.Fatalf("expected Unregister to remove the endpoint")
+	}
+	if r.Unregister(http.MethodPost, "/plugins/foo/items") {
+		t.Fatalf("expected second Unregister to report nothing removed")
+	}
+	if err := h.Reload(); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if got := liveStatus(h, http.MethodGet, "/plugins/foo/items"); got != http.StatusNotFound {
+		t.Fatalf("expected %d after unregister, got %d", http.StatusNotFound, got)
+	}
+	if got := liveStatus(h, http.MethodGet, "/core"); got != http.StatusOK {
+		t.Fatalf("expected core endpoint to keep serving, got %d", got)
+	}
+	desc := r.Describe()
+	if len(desc) != 1 || desc[0].Path != "/core" {
+		t.Fatalf("expected only /core to be described, got %+v", desc)
+	}
+}
+
+func TestLiveHandler_ReloadErrorKeepsPreviousTable(t *testing.T) {
+	r := New()
+	ok := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	RegisterHandler(r.EndpointGroup, GET(ok, "/core"))
+
+	h, err := r.LiveHandler()
+	if err != nil {
+		t.Fatalf("live handler error: %v", err)
+	}
+
+	RegisterHandler(r.EndpointGroup, GET(ok, "/core"))
+	if err := h.Reload(); err == nil || !strings.Contains(err.Error(), "duplicate route") {
+		t.Fatalf("expected duplicate route error, got %v", err)
+	}
+	if got := liveStatus(h, http.MethodGet, "/core"); got != http.StatusOK {
+		t.Fatalf("expected previous table to keep serving, got %d", got)
+	}
+
+	r.Unregister(http.MethodGet, "/core")
+	RegisterHandlerM(r.EndpointGroup, GETM(func(context.Context, struct{}, struct {
+		ID int `path:"idd"`
+	}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/users/:id"))
+	if err := h.Reload(); err == nil || !strings.Contains(err.Error(), `path tag "idd"`) {
+		t.Fatalf("expected registration error, got %v", err)
+	}
+	if err := h.Reload(); err != nil {
+		t.Fatalf("expected registration errors to be cleared, got %v", err)
+	}
+	if got := liveStatus(h, http.MethodGet, "/core"); got != http.StatusNotFound {
+		t.Fatalf("expected unregistered endpoint to be gone, got %d", got)
+	}
+}
+
+func TestLiveHandler_ConcurrentReload(t *testing.T) {
+	r := New()
+	ok := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	RegisterHandler(r.EndpointGroup, GET(ok, "/core"))
+
+	h, err := r.LiveHandler()
+	if err != nil {
+		t.Fatalf("live handler error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 100 {
+				if got := liveStatus(h, http.MethodGet, "/core"); got != http.StatusOK {
+					t.Errorf("expected %d, got %d", http.StatusOK, got)
+					return
+				}
+			}
+		})
+	}
+	for range 20 {
+		RegisterHandler(r.EndpointGroup, GET(ok, "/toggle"))
+		if err := h.Reload(); err != nil {
+			t.Fatalf("reload error: %v", err)
+		}
+		r.Unregister(http.MethodGet, "/toggle")
+		if err := h.Reload(); err != nil {
+			t.Fatalf("reload error: %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestLiveHandler_InvalidHostGroupStaysUnrouted(t *testing.T) {
+	r := New()
+	ok := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	RegisterHandler(r.EndpointGroup, GET(ok, "/core"))
+	h, err := r.LiveHandler()
+	if err != nil {
+		t.Fatalf("live handler error: %v", err)
+	}
+
+	tenant := r.Host("bad..example.com")
+	RegisterHandler(tenant, GET(ok, "/admin"))
+	if err := h.Reload(); err == nil || !strings.Contains(err.Error(), `invalid host pattern "bad..example.com"`) {
+		t.Fatalf("expected invalid host pattern error, got %v", err)
+	}
+	if err := h.Reload(); err != nil {
+		t.Fatalf("expected registration errors to be cleared, got %v", err)
+	}
+	if got := liveStatus(h, http.MethodGet, "/admin"); got != http.StatusNotFound {
+		t.Fatalf("expected endpoints of an invalid host group to stay unrouted, got %d", got)
+	}
+
+	RegisterHandler(tenant, GET(ok, "/late"))
+	if err := h.Reload(); err == nil || !strings.Contains(err.Error(), "register GET /late: invalid host pattern") {
+		t.Fatalf("expected later registrations on the group to fail, got %v", err)
+	}
+	if got := liveStatus(h, http.MethodGet, "/late"); got != http.StatusNotFound {
+		t.Fatalf("expected late endpoint to stay unrouted, got %d", got)
+	}
+}
+
+func TestLiveHandler_MetadataFollowsTable(t *testing.T) {
+	r := New()
+	ok := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	RegisterHandler(r.EndpointGroup, GET(ok, "/core"))
+	RegisterHandler(r.Group("/plugins/foo"), GET(ok, "/items"), WithName[struct{}, struct{}]("foo.items"))
+
+	h, err := r.LiveHandler()
+	if err != nil {
+		t.Fatalf("live handler error: %v", err)
+	}
+
+	r.UnregisterName("foo.items")
+	RegisterHandler(r.EndpointGroup, GET(ok, "/pending"))
+
+	// Until Reload, metadata describes the table being served.
+	assertLive := func(wantItems bool) {
+		t.Helper()
+		var paths []string
+		for _, d := range r.Describe() {
+			paths = append(paths, d.Path)
+		}
+		want := []string{"/core", "/pending"}
+		if wantItems {
+			want = []string{"/core", "/plugins/foo/items"}
+		}
+		if !slices.Equal(paths, want) {
+			t.Fatalf("expected described paths %v, got %v", want, paths)
+		}
+		var buf bytes.Buffer
+		if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+			t.Fatalf("gen ts error: %v", err)
+		}
+		if got := strings.Contains(buf.String(), "/plugins/foo/items"); got != wantItems {
+			t.Fatalf("expected generated client to contain the plugin path: %v, got %v", wantItems, got)
+		}
+		if _, err := r.URL("foo.items", nil); (err == nil) != wantItems {
+			t.Fatalf("expected URL to resolve: %v, got error %v", wantItems, err)
+		}
+	}
+	assertLive(true)
+	if got := liveStatus(h, http.MethodGet, "/plugins/foo/items"); got != http.StatusOK {
+		t.Fatalf("expected unregistered endpoint to serve until Reload, got %d", got)
+	}
+
+	if err := h.Reload(); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	assertLive(false)
+}
+
+func TestLiveHandler_UseDuringReload(t *testing.T) {
+	r := New()
+	ok := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	api := r.Group("/api")
+	RegisterHandler(api, GET(ok, "/core"))
+
+	h, err := r.LiveHandler()
+	if err != nil {
+		t.Fatalf("live handler error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range 50 {
+			api.Use(func(next http.Handler) http.Handler { return next })
+		}
+	})
+	for range 50 {
+		if err := h.Reload(); err != nil {
+			t.Fatalf("reload error: %v", err)
+		}
+		_ = r.Routes()
+	}
+	wg.Wait()
+}
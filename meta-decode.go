@@ -3,7 +3,10 @@ package httprpc
 import (
 	"fmt"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"slices"
 	"strings"
 )
 
@@ -14,70 +17,91 @@ type metaTag struct {
 	skip      bool
 }
 
-func decodeRequestMeta[Meta any](r *http.Request) (Meta, error) {
+// decodeRequestMeta decodes Meta from r's path params, headers, cookies, query
+// and remote address. trustedProxies is used to resolve `remote:"ip"` fields.
+func decodeRequestMeta[Meta any](r *http.Request, trustedProxies []netip.Prefix) (Meta, error) {
 	var meta Meta
-	mv := reflect.ValueOf(&meta).Elem()
-	mt := mv.Type()
-	if mv.Kind() != reflect.Struct {
-		return meta, fmt.Errorf("decode meta: request meta type %s must be a struct", mt.Kind())
+	plan := metaPlanFor(reflect.TypeFor[Meta]())
+	if plan.err != nil {
+		return meta, fmt.Errorf("decode meta: %w", plan.err)
+	}
+	if len(plan.fields) == 0 {
+		return meta, nil
 	}
 
-	pathParams, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
-
-	for i := range mt.NumField() {
-		field := mt.Field(i)
-		if !field.IsExported() {
-			continue
-		}
-		if field.Anonymous && deref(field.Type).Kind() == reflect.Struct {
-			continue
-		}
-
-		pathTag, err := parseMetaTag(mt, field, "path", true)
-		if err != nil {
-			return meta, err
-		}
-		headerTag, err := parseMetaTag(mt, field, "header", false)
-		if err != nil {
-			return meta, err
-		}
-		if pathTag.found && headerTag.found {
-			return meta, fmt.Errorf("%s.%s: cannot use both path and header tags", metaOwnerName(mt), field.Name)
-		}
-		if pathTag.found {
-			if pathTag.skip {
-				continue
-			}
-			val, ok := pathParams[pathTag.name]
+	mv := reflect.ValueOf(&meta).Elem()
+	pathParams, _ := r.Context().Value(pathParamsKey{}).(*pathParams)
+	var query url.Values
+	for _, f := range plan.fields {
+		switch f.source {
+		case metaSourcePath:
+			val, ok := pathParams.get(f.name)
+			vals := []string{val}
 			if !ok {
-				return meta, fmt.Errorf("missing path param %q", pathTag.name)
+				if !f.rules.hasDefault() {
+					if f.omitempty {
+						continue
+					}
+					return meta, fmt.Errorf("missing path param %q", f.name)
+				}
+				vals = f.rules.def
 			}
-			fv := mv.Field(i)
-			if !fv.CanSet() {
-				continue
+			if err := setMetaField(mv.Field(f.index), vals, f.rules); err != nil {
+				return meta, fmt.Errorf("decode path %s: %w", f.name, err)
 			}
-			if err := setFromStrings(fv, []string{val}); err != nil {
-				return meta, fmt.Errorf("decode path %s: %w", pathTag.name, err)
+		case metaSourceHeader:
+			vals := r.Header[f.key]
+			if len(vals) == 0 {
+				if !f.rules.hasDefault() {
+					if f.omitempty {
+						continue
+					}
+					return meta, fmt.Errorf("missing header %q", f.name)
+				}
+				vals = f.rules.def
 			}
-			continue
-		}
-		if headerTag.found {
-			if headerTag.skip {
-				continue
+			if err := setMetaField(mv.Field(f.index), vals, f.rules); err != nil {
+				return meta, fmt.Errorf("decode header %s: %w", f.name, err)
 			}
-			vals := r.Header.Values(headerTag.name)
-			if len(vals) == 0 {
-				if headerTag.omitempty {
+		case metaSourceCookie:
+			var vals []string
+			if cookie, err := r.Cookie(f.name); err == nil {
+				vals = []string{cookie.Value}
+			} else if f.rules.hasDefault() {
+				vals = f.rules.def
+			} else {
+				if f.omitempty {
 					continue
 				}
-				return meta, fmt.Errorf("missing header %q", headerTag.name)
+				return meta, fmt.Errorf("missing cookie %q", f.name)
+			}
+			if err := setMetaField(mv.Field(f.index), vals, f.rules); err != nil {
+				return meta, fmt.Errorf("decode cookie %s: %w", f.name, err)
 			}
-			fv := mv.Field(i)
-			if !fv.CanSet() {
-				continue
+		case metaSourceQuery:
+			if query == nil {
+				query = r.URL.Query()
 			}
-			if err := setFromStrings(fv, vals); err != nil {
-				return meta, fmt.Errorf("decode header %s: %w", headerTag.name, err)
+			vals := query[f.name]
+			if len(vals) == 0 {
+				if !f.rules.hasDefault() {
+					if f.omitempty {
+						continue
+					}
+					return meta, fmt.Errorf("missing query param %q", f.name)
+				}
+				vals = f.rules.def
+			}
+			if err := setMetaField(mv.Field(f.index), vals, f.rules); err != nil {
+				return meta, fmt.Errorf("decode query %s: %w", f.name, err)
+			}
+		case metaSourceRemote:
+			val := r.RemoteAddr
+			if f.name == remoteIP {
+				val = clientIP(r, trustedProxies)
+			}
+			if err := setMetaField(mv.Field(f.index), []string{val}, f.rules); err != nil {
+				return meta, fmt.Errorf("decode remote %s: %w", f.name, err)
 			}
 		}
 	}
@@ -85,69 +109,44 @@ func decodeRequestMeta[Meta any](r *http.Request) (Meta, error) {
 	return meta, nil
 }
 
-func validateMetaType(meta reflect.Type, path string) error {
+func setMetaField(v reflect.Value, vals []string, rules fieldRules) error {
+	if err := setFromStrings(v, vals); err != nil {
+		return err
+	}
+	return rules.checkEnum(v)
+}
+
+// validateMetaType checks that meta can be decoded for a route with the given
+// path and host params. It compiles and caches meta's decode plan.
+func validateMetaType(meta reflect.Type, path string, hostParams []string) error {
 	if meta == nil {
 		return nil
 	}
-	if meta.Kind() == reflect.Pointer {
-		return fmt.Errorf("request meta type must be a struct (non-pointer)")
+	plan := metaPlanFor(meta)
+	if plan.err != nil {
+		return plan.err
 	}
-	if meta.Kind() != reflect.Struct {
-		return fmt.Errorf("request meta type %s must be a struct", meta.Kind())
+	if err := validatePlanFor(meta, true).err; err != nil {
+		return err
 	}
 
 	pattern, err := parseRoutePattern(path)
 	if err != nil {
 		return err
 	}
-	pathParams := map[string]struct{}{}
-	for _, name := range pattern.params {
-		pathParams[name] = struct{}{}
-	}
-
-	seenPath := map[string]struct{}{}
-	seenHeader := map[string]struct{}{}
-
-	for i := range meta.NumField() {
-		field := meta.Field(i)
-		if !field.IsExported() {
+	for _, f := range plan.fields {
+		if f.source != metaSourcePath {
 			continue
 		}
-		if field.Anonymous && deref(field.Type).Kind() == reflect.Struct {
-			continue
-		}
-
-		pathTag, err := parseMetaTag(meta, field, "path", true)
-		if err != nil {
-			return err
-		}
-		headerTag, err := parseMetaTag(meta, field, "header", false)
-		if err != nil {
-			return err
-		}
-		if pathTag.found && headerTag.found {
-			return fmt.Errorf("%s.%s: cannot use both path and header tags", metaOwnerName(meta), field.Name)
+		seg, ok := pattern.param(f.name)
+		if !ok && !slices.Contains(hostParams, f.name) {
+			return fmt.Errorf("path tag %q does not match route %s", f.name, path)
 		}
-		if pathTag.found {
-			if pathTag.skip {
-				continue
-			}
-			if _, ok := pathParams[pathTag.name]; !ok {
-				return fmt.Errorf("path tag %q does not match route %s", pathTag.name, path)
-			}
-			if _, ok := seenPath[pathTag.name]; ok {
-				return fmt.Errorf("path tag %q is used more than once", pathTag.name)
-			}
-			seenPath[pathTag.name] = struct{}{}
+		if seg.optional && !f.omitempty && !f.rules.hasDefault() {
+			return fmt.Errorf("path tag %q binds an optional param and must be omitempty or have a default", f.name)
 		}
-		if headerTag.found {
-			if headerTag.skip {
-				continue
-			}
-			if _, ok := seenHeader[headerTag.name]; ok {
-				return fmt.Errorf("header tag %q is used more than once", headerTag.name)
-			}
-			seenHeader[headerTag.name] = struct{}{}
+		if seg.kind == segmentCatchAll && deref(meta.Field(f.index).Type).Kind() != reflect.String {
+			return fmt.Errorf("path tag %q binds a catch-all param and must be a string", f.name)
 		}
 	}
 
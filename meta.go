@@ -4,15 +4,25 @@ import "reflect"
 
 // EndpointMeta contains metadata about an endpoint.
 type EndpointMeta struct {
+	// Name is set with WithName and used for reverse URL building.
+	Name   string
 	Method string
 	Path   string
 
+	// Host and Headers are the group matchers the endpoint was registered under.
+	Host    string
+	Headers map[string]string
+
 	Req  reflect.Type
 	Meta reflect.Type
-	Res  reflect.Type
+	// Res is the response body type: T for handlers returning Response[T].
+	Res reflect.Type
 
 	Consumes []string
 	Produces []string
+
+	// Stream is set for streaming endpoints; Res is then the item type.
+	Stream StreamKind
 }
 
 // TypeRef represents a reference to a Go type.
@@ -22,17 +32,34 @@ type TypeRef struct {
 	PkgPath string
 }
 
+// PathParamDescription describes a path param declared by a route pattern.
+type PathParamDescription struct {
+	Name string
+	// Constraint is "int", "uint", "uuid", a regular expression, or empty.
+	Constraint string
+	Optional   bool
+	CatchAll   bool
+}
+
 // EndpointDescription describes an endpoint for TypeScript generation.
 type EndpointDescription struct {
+	Name   string
 	Method string
 	Path   string
 
+	Host    string
+	Headers map[string]string
+
+	PathParams []PathParamDescription
+
 	Req  TypeRef
 	Meta TypeRef
 	Res  TypeRef
 
 	Consumes []string
 	Produces []string
+
+	Stream StreamKind
 }
 
 func typeRef(t reflect.Type) TypeRef {
@@ -45,3 +72,23 @@ func typeRef(t reflect.Type) TypeRef {
 		PkgPath: t.PkgPath(),
 	}
 }
+
+func describePathParams(path string) []PathParamDescription {
+	pattern, err := parseRoutePattern(path)
+	if err != nil || len(pattern.params) == 0 {
+		return nil
+	}
+	out := make([]PathParamDescription, 0, len(pattern.params))
+	for _, seg := range pattern.segments {
+		if seg.kind == segmentStatic {
+			continue
+		}
+		out = append(out, PathParamDescription{
+			Name:       seg.value,
+			Constraint: seg.constraint.key(),
+			Optional:   seg.optional,
+			CatchAll:   seg.kind == segmentCatchAll,
+		})
+	}
+	return out
+}
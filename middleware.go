@@ -21,5 +21,8 @@ func (eg *EndpointGroup) Use(middleware Middleware, middlewareOpts ...Middleware
 	for _, opt := range middlewareOpts {
 		opt.apply(out)
 	}
+	root := eg.rootGroup()
+	root.mu.Lock()
 	eg.Middlewares = append(eg.Middlewares, out)
+	root.mu.Unlock()
 }
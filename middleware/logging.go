@@ -36,6 +36,12 @@ func (rw *responseRecorder) Write(p []byte) (int, error) {
 	return n, nil
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, for
+// flushing streams and hijacking WebSocket connections.
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Logging logs request/response metadata using slog.
 // If logger is nil, slog.Default is used.
 func Logging(logger *slog.Logger) httprpc.Middleware {
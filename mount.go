@@ -0,0 +1,146 @@
+package httprpc
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Mount attaches h under prefix. Requests for the prefix or any path below it
+// are passed to h, whatever their method, with the prefix stripped from the URL
+// path. The group's middlewares apply, and params in the prefix are readable via
+// PathParam. Registering a route under a mounted prefix is a build error.
+//
+// Mounting a *Router builds it along with the parent and adds its endpoints,
+// prefixed, to Describe and TypeScript generation. Its build errors, including
+// registration errors already recorded when Mount is called, are returned by
+// the parent's Handler.
+func (eg *EndpointGroup) Mount(prefix string, h http.Handler) {
+	if h == nil {
+		eg.registerError(fmt.Errorf("mount %s: nil handler", eg.Prefix+prefix))
+		return
+	}
+	if sub, ok := h.(*Router); ok {
+		if err := sub.registrationErr(); err != nil {
+			eg.registerError(fmt.Errorf("mount %s: %w", eg.Prefix+prefix, err))
+		}
+	}
+
+	eg.addEndpoint(&endpoint{
+		Path:    eg.Prefix + prefix,
+		Handler: h,
+		Group:   eg,
+		Mount:   true,
+	}, nil)
+}
+
+// ServeHTTP serves the request with the router's handler, built on first use.
+// Build errors are logged and answered with 500; call Handler to surface them.
+// This includes a Router wrapped in another handler before being mounted,
+// since the parent cannot see through the wrapper.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.serveOnce.Do(func() {
+		h, err := r.buildHandler()
+		if err != nil {
+			slog.Error("failed to build router handler", "error", err)
+			h = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			})
+		}
+		r.served = h
+	})
+	r.served.ServeHTTP(w, req)
+}
+
+// parseMountPattern parses a mount prefix into a pattern ending in an unnamed
+// catch-all. The prefix may contain params but no optional or catch-all segments.
+func parseMountPattern(prefix string) (*routePattern, error) {
+	p, err := parseRoutePattern(prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range p.segments {
+		if seg.optional || seg.kind == segmentCatchAll {
+			return nil, fmt.Errorf("mount prefix %s cannot contain optional or catch-all params", p.path)
+		}
+	}
+	segments := append(p.segments[:len(p.segments):len(p.segments)], routeSegment{kind: segmentCatchAll})
+	return &routePattern{
+		path:     strings.TrimSuffix(p.path, "/") + "/*",
+		segments: segments,
+		params:   p.params,
+	}, nil
+}
+
+// stripSegments serves h with the first n path segments removed from the URL.
+func stripSegments(h http.Handler, n int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rest := strings.TrimLeft(req.URL.Path, "/")
+		for range n {
+			i := strings.IndexByte(rest, '/')
+			if i < 0 {
+				rest = ""
+				break
+			}
+			rest = rest[i+1:]
+		}
+
+		r2 := new(http.Request)
+		*r2 = *req
+		r2.URL = new(url.URL)
+		*r2.URL = *req.URL
+		r2.URL.Path = "/" + rest
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// endpointMetas returns the metas of registered endpoints followed by those of
+// mounted routers, with paths and group matchers adjusted to the mount point.
+func (r *Router) endpointMetas() []*EndpointMeta {
+	handlers, metas := r.snapshot()
+	return appendMountedMetas(metas, handlers, (*Router).endpointMetas)
+}
+
+// liveMetas returns the metas of the route table last built by Handler or
+// LiveHandler.Reload, or those of the current registrations if there is none.
+func (r *Router) liveMetas() []*EndpointMeta {
+	if table := r.table.Load(); table != nil {
+		return table.metas
+	}
+	return r.endpointMetas()
+}
+
+// appendMountedMetas appends to out the metas subMetas returns for each Router
+// mounted in handlers, adjusted to the mount point.
+func appendMountedMetas(out []*EndpointMeta, handlers []*endpoint, subMetas func(*Router) []*EndpointMeta) []*EndpointMeta {
+	for _, e := range handlers {
+		if e == nil || !e.Mount {
+			continue
+		}
+		sub, ok := e.Handler.(*Router)
+		if !ok {
+			continue
+		}
+		prefix := strings.TrimSuffix(normalizeRoutePath(e.Path), "/")
+		for _, m := range subMetas(sub) {
+			if m == nil {
+				continue
+			}
+			cp := *m
+			cp.Path = normalizeRoutePath(prefix + normalizeRoutePath(m.Path))
+			if cp.Host == "" {
+				cp.Host = e.Group.match.hostPattern()
+			}
+			if headers := e.Group.match.headerMap(); headers != nil {
+				maps.Copy(headers, m.Headers)
+				cp.Headers = headers
+			}
+			out = append(out, &cp)
+		}
+	}
+	return out
+}
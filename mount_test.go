@@ -0,0 +1,162 @@
+package httprpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMount_StripsPrefixAndAppliesMiddlewares(t *testing.T) {
+	r := New()
+	admin := r.Group("/admin")
+	admin.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Admin", req.URL.Path)
+			next.ServeHTTP(w, req)
+		})
+	})
+	admin.Mount("/files/:bucket", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bucket, _ := PathParam(req.Context(), "bucket")
+		_, _ = io.WriteString(w, req.Method+" "+bucket+" "+req.URL.Path)
+	}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: http.MethodGet, path: "/admin/files/logs/2024/app.log", want: "GET logs /2024/app.log"},
+		{method: http.MethodDelete, path: "/admin/files/logs/dir/", want: "DELETE logs /dir/"},
+		{method: http.MethodPost, path: "/admin/files/logs", want: "POST logs /"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+		if got := rec.Body.String(); got != tt.want {
+			t.Fatalf("%s %s: expected %q, got %q", tt.method, tt.path, tt.want, got)
+		}
+		if got := rec.Header().Get("X-Admin"); got != tt.path {
+			t.Fatalf("%s %s: middleware saw %q", tt.method, tt.path, got)
+		}
+	}
+}
+
+func TestMount_Router(t *testing.T) {
+	sub := New()
+	RegisterHandler(sub.EndpointGroup, GET(func(context.Context, struct{}) (string, error) {
+		return "pong", nil
+	}, "/ping"))
+
+	r := New()
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (string, error) {
+		return "root", nil
+	}, "/health"))
+	r.Group("/api").Mount("/v2", sub)
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/ping", http.NoBody))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "pong") {
+		t.Fatalf("expected pong, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	desc := r.Describe()
+	if len(desc) != 2 {
+		t.Fatalf("expected 2 descriptions, got %d", len(desc))
+	}
+	if desc[1].Path != "/api/v2/ping" {
+		t.Fatalf("expected mounted path, got %q", desc[1].Path)
+	}
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "/api/v2/ping") {
+		t.Fatalf("expected mounted endpoint in generated client:\n%s", buf.String())
+	}
+}
+
+func TestMount_Conflicts(t *testing.T) {
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	get := GET(func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }, "/static/app.js")
+
+	tests := []struct {
+		name  string
+		setup func(*Router)
+		want  string
+	}{
+		{
+			name: "route-below-mount",
+			setup: func(r *Router) {
+				r.Mount("/static", noop)
+				RegisterHandler(r.EndpointGroup, get)
+			},
+			want: "conflicts with mount /static",
+		},
+		{
+			name: "duplicate-mount",
+			setup: func(r *Router) {
+				r.Mount("/static", noop)
+				r.Group("/static").Mount("/", noop)
+			},
+			want: "duplicate mount",
+		},
+		{
+			name: "catch-all-at-mount",
+			setup: func(r *Router) {
+				r.Mount("/static", noop)
+				RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+					return struct{}{}, nil
+				}, "/static/*path"))
+			},
+			want: "ambiguous route",
+		},
+		{
+			name:  "optional-prefix",
+			setup: func(r *Router) { r.Mount("/static/:dir?", noop) },
+			want:  "cannot contain optional or catch-all params",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New()
+			tt.setup(r)
+			if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMount_SubRouterRegistrationError(t *testing.T) {
+	sub := New()
+	sub.Host("bad..example.com")
+
+	r := New()
+	r.Mount("/sub", sub)
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), "mount /sub: invalid host pattern") {
+		t.Fatalf("expected the sub-router error from Handler, got %v", err)
+	}
+
+	strict := New(WithStrictRegistration())
+	defer func() {
+		if p := recover(); p == nil || !strings.Contains(fmt.Sprint(p), "mount /sub: invalid host pattern") {
+			t.Fatalf("expected Mount to panic with the sub-router error, got %v", p)
+		}
+	}()
+	strict.Mount("/sub", sub)
+}
@@ -0,0 +1,292 @@
+//nolint:mnd,gosec // byte values and integer widths are fixed by the wire format
+package httprpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+)
+
+// MsgpackCodec encodes bodies as MessagePack (https://msgpack.org). Fields
+// are named by their json tags and follow the encoding/json rules, so the
+// generated TypeScript types describe both encodings. []byte is sent as bin,
+// time.Time and other TextMarshalers as strings. GET requests decode the
+// query string as in DefaultCodec.
+type MsgpackCodec[Req any, Res any] struct {
+	DefaultCodec[Req, Res]
+
+	// MaxBytes limits the request body size. Zero means DefaultMaxBodyBytes.
+	MaxBytes int64
+	// MaxDepth limits how deeply arrays and maps may nest. Zero means DefaultMaxJSONDepth.
+	MaxDepth int
+}
+
+// Consumes returns the content types this codec can decode.
+func (c MsgpackCodec[Req, Res]) Consumes() []string {
+	return []string{"application/msgpack", "application/vnd.msgpack", "application/x-msgpack"}
+}
+
+// Produces returns the content types this codec can encode.
+func (c MsgpackCodec[Req, Res]) Produces() []string { return []string{"application/msgpack"} }
+
+// DecodeBody decodes a MessagePack body into the request type.
+func (c MsgpackCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
+	return decodeBinaryBody[Req](r, msgpackFormat, c.MaxBytes, c.MaxDepth)
+}
+
+// Encode encodes the response as MessagePack.
+func (c MsgpackCodec[Req, Res]) Encode(w http.ResponseWriter, res Res) error {
+	return writeBinary(w, msgpackFormat, "application/msgpack", c.Status, res)
+}
+
+// EncodeError encodes an error as MessagePack with the same problem members
+// and status as DefaultCodec.
+func (c MsgpackCodec[Req, Res]) EncodeError(w http.ResponseWriter, err error) error {
+	p := problemFor(err)
+	return writeBinary(w, msgpackFormat, "application/msgpack", p.Status, p)
+}
+
+var msgpackFormat = binaryFormat{
+	name:      "msgpack",
+	newWriter: func() binaryWriter { return &msgpackWriter{} },
+	newReader: func(data []byte) binaryReader { return &msgpackReader{data: data} },
+}
+
+// msgpackWriter writes the smallest representation of each value.
+type msgpackWriter struct {
+	buf []byte
+}
+
+func (w *msgpackWriter) bytes() []byte { return w.buf }
+
+func (w *msgpackWriter) writeNil() { w.buf = append(w.buf, 0xc0) }
+
+func (w *msgpackWriter) writeBool(b bool) {
+	if b {
+		w.buf = append(w.buf, 0xc3)
+		return
+	}
+	w.buf = append(w.buf, 0xc2)
+}
+
+func (w *msgpackWriter) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		w.writeUint(uint64(i))
+	case i >= -32:
+		w.buf = append(w.buf, byte(i)) // negative fixint
+	case i >= math.MinInt8:
+		w.buf = append(w.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xd2), uint32(i))
+	default:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xd3), uint64(i))
+	}
+}
+
+func (w *msgpackWriter) writeUint(u uint64) {
+	switch {
+	case u <= math.MaxInt8:
+		w.buf = append(w.buf, byte(u)) // positive fixint
+	case u <= math.MaxUint8:
+		w.buf = append(w.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xce), uint32(u))
+	default:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xcf), u)
+	}
+}
+
+func (w *msgpackWriter) writeFloat32(f float32) {
+	w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xca), math.Float32bits(f))
+}
+
+func (w *msgpackWriter) writeFloat64(f float64) {
+	w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xcb), math.Float64bits(f))
+}
+
+func (w *msgpackWriter) writeString(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		w.buf = append(w.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xda), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdb), uint32(n))
+	}
+	w.buf = append(w.buf, s...)
+}
+
+func (w *msgpackWriter) writeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xc5), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xc6), uint32(n))
+	}
+	w.buf = append(w.buf, b...)
+}
+
+func (w *msgpackWriter) writeArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		w.buf = append(w.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xdc), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdd), uint32(n))
+	}
+}
+
+func (w *msgpackWriter) writeMapHeader(n int) {
+	switch {
+	case n <= 15:
+		w.buf = append(w.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xde), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdf), uint32(n))
+	}
+}
+
+type msgpackReader struct {
+	data []byte
+	off  int
+}
+
+func (r *msgpackReader) remaining() int { return len(r.data) - r.off }
+
+func (r *msgpackReader) take(n uint64) ([]byte, error) {
+	if n > uint64(r.remaining()) {
+		return nil, fmt.Errorf("offset %d: %w", r.off, io.ErrUnexpectedEOF)
+	}
+	b := r.data[r.off : r.off+int(n)]
+	r.off += int(n)
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (r *msgpackReader) uint(size int) (uint64, error) {
+	b, err := r.take(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (r *msgpackReader) next() (binaryToken, error) {
+	start := r.off
+	b, err := r.take(1)
+	if err != nil {
+		return binaryToken{}, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return binaryToken{kind: binUint, u: uint64(c)}, nil
+	case c >= 0xe0:
+		return binaryToken{kind: binInt, i: int64(int8(c))}, nil
+	case c&0xe0 == 0xa0:
+		return r.str(binString, uint64(c&0x1f))
+	case c&0xf0 == 0x90:
+		return r.container(binArray, uint64(c&0x0f))
+	case c&0xf0 == 0x80:
+		return r.container(binMap, uint64(c&0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return binaryToken{kind: binNil}, nil
+	case 0xc2, 0xc3:
+		return binaryToken{kind: binBool, b: c == 0xc3}, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := r.uint(1 << (c - 0xcc))
+		return binaryToken{kind: binUint, u: u}, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, err := r.uint(size)
+		shift := 64 - 8*size
+		return binaryToken{kind: binInt, i: int64(u<<shift) >> shift}, err
+	case 0xca:
+		u, err := r.uint(4)
+		return binaryToken{kind: binFloat, f: float64(math.Float32frombits(uint32(u)))}, err
+	case 0xcb:
+		u, err := r.uint(8)
+		return binaryToken{kind: binFloat, f: math.Float64frombits(u)}, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (c - 0xd9))
+		if err != nil {
+			return binaryToken{}, err
+		}
+		return r.str(binString, n)
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (c - 0xc4))
+		if err != nil {
+			return binaryToken{}, err
+		}
+		return r.str(binBytes, n)
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (c - 0xdc))
+		if err != nil {
+			return binaryToken{}, err
+		}
+		return r.container(binArray, n)
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (c - 0xde))
+		if err != nil {
+			return binaryToken{}, err
+		}
+		return r.container(binMap, n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return r.ext(1 << (c - 0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := r.uint(1 << (c - 0xc7))
+		if err != nil {
+			return binaryToken{}, err
+		}
+		return r.ext(n)
+	default:
+		return binaryToken{}, fmt.Errorf("offset %d: invalid type byte 0x%02x", start, c)
+	}
+}
+
+func (r *msgpackReader) str(kind binaryKind, n uint64) (binaryToken, error) {
+	s, err := r.take(n)
+	return binaryToken{kind: kind, s: s}, err
+}
+
+func (r *msgpackReader) container(kind binaryKind, n uint64) (binaryToken, error) {
+	units := uint64(1)
+	if kind == binMap {
+		units = 2
+	}
+	if !lengthFits(n, units, r.remaining()) {
+		return binaryToken{}, fmt.Errorf("offset %d: %s of %d items: %w", r.off, kind, n, io.ErrUnexpectedEOF)
+	}
+	return binaryToken{kind: kind, n: int(n)}, nil
+}
+
+func (r *msgpackReader) ext(n uint64) (binaryToken, error) {
+	typ, err := r.take(1)
+	if err != nil {
+		return binaryToken{}, err
+	}
+	data, err := r.take(n)
+	return binaryToken{kind: binExt, ext: int8(typ[0]), s: data}, err
+}
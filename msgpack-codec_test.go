@@ -0,0 +1,268 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vectors from the MessagePack specification's format table.
+var msgpackVectors = []struct {
+	value any
+	hex   string
+}{
+	{nil, "c0"},
+	{false, "c2"},
+	{true, "c3"},
+	{int64(0), "00"},
+	{int64(127), "7f"},
+	{int64(128), "cc80"},
+	{int64(255), "ccff"},
+	{int64(256), "cd0100"},
+	{int64(65535), "cdffff"},
+	{int64(65536), "ce00010000"},
+	{int64(4294967295), "ceffffffff"},
+	{int64(4294967296), "cf0000000100000000"},
+	{uint64(math.MaxUint64), "cfffffffffffffffff"},
+	{int64(-1), "ff"},
+	{int64(-32), "e0"},
+	{int64(-33), "d0df"},
+	{int64(-128), "d080"},
+	{int64(-129), "d1ff7f"},
+	{int64(-32768), "d18000"},
+	{int64(-32769), "d2ffff7fff"},
+	{int64(-2147483648), "d280000000"},
+	{int64(-2147483649), "d3ffffffff7fffffff"},
+	{float32(1.5), "ca3fc00000"},
+	{1.5, "cb3ff8000000000000"},
+	{"", "a0"},
+	{"a", "a161"},
+	{strings.Repeat("x", 31), "bf" + strings.Repeat("78", 31)},
+	{strings.Repeat("x", 32), "d920" + strings.Repeat("78", 32)},
+	{strings.Repeat("x", 256), "da0100" + strings.Repeat("78", 256)},
+	{[]byte{}, "c400"},
+	{[]byte{1, 2}, "c4020102"},
+	{[]any{}, "90"},
+	{[]any{int64(1), int64(2)}, "920102"},
+	{make([]any, 16), "dc0010" + strings.Repeat("c0", 16)},
+	{map[string]any{}, "80"},
+	{map[string]any{"a": int64(1), "b": "c"}, "82a16101a162a163"},
+}
+
+func TestMsgpack_SpecVectors(t *testing.T) {
+	for _, tc := range msgpackVectors {
+		want, err := hex.DecodeString(tc.hex)
+		if err != nil {
+			t.Fatalf("bad vector %s: %v", tc.hex, err)
+		}
+		got, err := msgpackFormat.marshal(tc.value)
+		if err != nil {
+			t.Fatalf("marshal %#v: %v", tc.value, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("marshal %#v: got %x, want %s", tc.value, got, tc.hex)
+		}
+
+		var decoded any
+		if err := msgpackFormat.unmarshal(want, &decoded, DefaultMaxJSONDepth); err != nil {
+			t.Fatalf("unmarshal %s: %v", tc.hex, err)
+		}
+		wantDecoded := tc.value
+		if f, ok := wantDecoded.(float32); ok {
+			wantDecoded = float64(f)
+		}
+		if !reflect.DeepEqual(decoded, wantDecoded) {
+			t.Errorf("unmarshal %s: got %#v, want %#v", tc.hex, decoded, wantDecoded)
+		}
+	}
+}
+
+func TestMsgpack_DecodesWiderEncodings(t *testing.T) {
+	cases := []struct {
+		hex  string
+		want any
+	}{
+		{"d001", int64(1)},   // int8 holding a positive value
+		{"cd0001", int64(1)}, // non-minimal uint16
+		{"d3ffffffffffffffff", int64(-1)},
+		{"db00000001 61", "a"},           // str32
+		{"c6000000020102", []byte{1, 2}}, // bin32
+		{"dd0000000101", []any{int64(1)}},
+		{"df00000001a16101", map[string]any{"a": int64(1)}},
+	}
+	for _, tc := range cases {
+		data, _ := hex.DecodeString(strings.ReplaceAll(tc.hex, " ", ""))
+		var got any
+		if err := msgpackFormat.unmarshal(data, &got, DefaultMaxJSONDepth); err != nil {
+			t.Fatalf("unmarshal %s: %v", tc.hex, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("unmarshal %s: got %#v, want %#v", tc.hex, got, tc.want)
+		}
+	}
+
+	for _, h := range []string{"", "c1", "cc", "a261", "dd000000ff", "0101", "d4ff01"} {
+		data, _ := hex.DecodeString(h)
+		var v any
+		if err := msgpackFormat.unmarshal(data, &v, DefaultMaxJSONDepth); err == nil {
+			t.Errorf("expected error for %q, got %#v", h, v)
+		}
+	}
+}
+
+var errTestConflict = errors.New("order already exists")
+
+type binaryLine struct {
+	SKU   string  `json:"sku"`
+	Qty   int     `json:"qty"`
+	Price float64 `json:"price"`
+}
+
+type binaryAudit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type binaryOrder struct {
+	binaryAudit
+	ID       int               `json:"id"`
+	Customer *string           `json:"customer"`
+	Lines    []binaryLine      `json:"lines"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Note     string            `json:"note,omitempty"`
+	Blob     []byte            `json:"blob"`
+	Secret   string            `json:"-"`
+	Counts   map[int]uint8     `json:"counts"`
+}
+
+func sampleBinaryOrder() binaryOrder {
+	customer := "ada"
+	return binaryOrder{
+		binaryAudit: binaryAudit{CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		ID:          7,
+		Customer:    &customer,
+		Lines:       []binaryLine{{SKU: "a-1", Qty: 2, Price: 9.5}, {SKU: "b-2", Qty: 1, Price: 0.1}},
+		Blob:        []byte{0xde, 0xad},
+		Counts:      map[int]uint8{1: 2, 10: 20},
+	}
+}
+
+func TestMsgpack_FollowsJSONTags(t *testing.T) {
+	data, err := msgpackFormat.marshal(sampleBinaryOrder())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var generic map[string]any
+	if err := msgpackFormat.unmarshal(data, &generic, DefaultMaxJSONDepth); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	keys := make([]string, 0, len(generic))
+	for k := range generic {
+		keys = append(keys, k)
+	}
+	for _, want := range []string{"created_at", "id", "customer", "lines", "blob", "counts"} {
+		if _, ok := generic[want]; !ok {
+			t.Fatalf("expected key %q, got %v", want, keys)
+		}
+	}
+	for _, unwanted := range []string{"labels", "note", "Secret", "binaryAudit"} {
+		if _, ok := generic[unwanted]; ok {
+			t.Fatalf("unexpected key %q in %v", unwanted, keys)
+		}
+	}
+	if generic["created_at"] != "2024-05-01T12:00:00Z" {
+		t.Fatalf("expected time as RFC 3339 string, got %#v", generic["created_at"])
+	}
+	if !reflect.DeepEqual(generic["counts"], map[string]any{"1": int64(2), "10": int64(20)}) {
+		t.Fatalf("expected integer map keys as strings, got %#v", generic["counts"])
+	}
+
+	var back binaryOrder
+	if err := msgpackFormat.unmarshal(data, &back, DefaultMaxJSONDepth); err != nil {
+		t.Fatalf("unmarshal struct: %v", err)
+	}
+	if !reflect.DeepEqual(back, sampleBinaryOrder()) {
+		t.Fatalf("round trip mismatch:\n got %#v\nwant %#v", back, sampleBinaryOrder())
+	}
+}
+
+func TestMsgpack_ReportsFieldPath(t *testing.T) {
+	data, err := msgpackFormat.marshal(map[string]any{
+		"lines": []any{map[string]any{"qty": 1}, map[string]any{"QTY": "two"}},
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var order binaryOrder
+	err = msgpackFormat.unmarshal(data, &order, DefaultMaxJSONDepth)
+	if err == nil || !strings.Contains(err.Error(), "/lines/1/QTY: cannot decode string into int") {
+		t.Fatalf("expected path in error, got %v", err)
+	}
+
+	data, _ = msgpackFormat.marshal(map[string]any{"id": 300})
+	var small struct {
+		ID int8 `json:"id"`
+	}
+	if err := msgpackFormat.unmarshal(data, &small, DefaultMaxJSONDepth); err == nil || !strings.Contains(err.Error(), "overflows int8") {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+}
+
+func TestMsgpackCodec_Endpoint(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(_ context.Context, in binaryOrder) (binaryOrder, error) {
+		if in.ID == 0 {
+			return binaryOrder{}, StatusError{Status: http.StatusConflict, Err: errTestConflict}
+		}
+		return in, nil
+	}, "/orders"), WithCodecs[binaryOrder, binaryOrder](
+		DefaultCodec[binaryOrder, binaryOrder]{},
+		MsgpackCodec[binaryOrder, binaryOrder]{},
+	))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	body, _ := msgpackFormat.marshal(sampleBinaryOrder())
+	req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-msgpack")
+	req.Header.Set("Accept", "application/msgpack")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/msgpack" {
+		t.Fatalf("expected 200 application/msgpack, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.Equal(rec.Body.Bytes(), body) {
+		t.Fatalf("expected echoed body %x, got %x", body, rec.Body.Bytes())
+	}
+
+	empty, _ := msgpackFormat.marshal(map[string]any{})
+	req = httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(empty))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/msgpack")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+	var errBody map[string]any
+	if err := msgpackFormat.unmarshal(rec.Body.Bytes(), &errBody, DefaultMaxJSONDepth); err != nil {
+		t.Fatalf("expected msgpack error body: %v", err)
+	}
+	if errBody["detail"] != errTestConflict.Error() || errBody["status"] != int64(http.StatusConflict) {
+		t.Fatalf("unexpected error body %#v", errBody)
+	}
+
+	desc := r.Describe()[0]
+	if !reflect.DeepEqual(desc.Produces, []string{"application/json", "application/msgpack"}) {
+		t.Fatalf("unexpected produces %v", desc.Produces)
+	}
+}
@@ -0,0 +1,179 @@
+package httprpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// NDJSONHandler returns the items of a streamed list response. The sequence
+// is consumed while the response is written, so items need not be buffered.
+// An error yielded before the first item becomes a normal error response;
+// later errors end the stream with a final {"$error": problem} record.
+type NDJSONHandler[Req, T any] func(ctx context.Context, req Req) iter.Seq2[T, error]
+
+// NDJSONEndpoint streams a newline-delimited JSON response.
+type NDJSONEndpoint[Req, T any] struct {
+	Handler NDJSONHandler[Req, T]
+	Path    string
+	// Method is GET unless set otherwise; requests of other methods are
+	// decoded from a JSON body.
+	Method string
+}
+
+// NDJSON creates a GET NDJSONEndpoint. The request is decoded from the query
+// string.
+func NDJSON[Req, T any](handler NDJSONHandler[Req, T], path string) NDJSONEndpoint[Req, T] {
+	return NDJSONEndpoint[Req, T]{Handler: handler, Path: path, Method: http.MethodGet}
+}
+
+// NDJSONContentType is the media type of newline-delimited JSON responses.
+const NDJSONContentType = "application/x-ndjson"
+
+// DefaultFlushInterval is how often buffered NDJSON items are flushed unless
+// WithFlushInterval says otherwise.
+const DefaultFlushInterval = 100 * time.Millisecond
+
+// RegisterNDJSON registers a newline-delimited JSON endpoint with the endpoint
+// group. Items are flushed at least every flush interval; see
+// WithFlushInterval.
+func RegisterNDJSON[Req, T any](eg *EndpointGroup, in NDJSONEndpoint[Req, T], opts ...StreamOption) {
+	o := newStreamOptions(opts)
+	method := in.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := eg.Prefix + in.Path
+	if err := compileRequestPlans(reflect.TypeFor[Req]()); err != nil {
+		eg.registerError(fmt.Errorf("register %s %s: invalid request %s: %w", method, path, reflect.TypeFor[Req](), err))
+		return
+	}
+
+	var consumes []string
+	if method != http.MethodGet && method != http.MethodHead {
+		consumes = []string{"application/json"}
+	}
+	eg.addEndpoint(&endpoint{
+		Path:       path,
+		Method:     method,
+		Source:     funcSource(in.Handler),
+		Handler:    adaptNDJSONHandler(in.Handler, o.flushInterval, eg.handlerConfig()),
+		Group:      eg,
+		NoAutoHead: true,
+	}, &EndpointMeta{
+		Name:     o.name,
+		Method:   method,
+		Path:     path,
+		Host:     eg.match.hostPattern(),
+		Headers:  eg.match.headerMap(),
+		Req:      reflect.TypeFor[Req](),
+		Res:      reflect.TypeFor[T](),
+		Consumes: consumes,
+		Produces: []string{NDJSONContentType},
+		Stream:   StreamNDJSON,
+	})
+}
+
+func adaptNDJSONHandler[Req, T any](handler NDJSONHandler[Req, T], flushInterval time.Duration, cfg handlerConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeStreamRequest[Req](w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		out := &ndjsonWriter{w: w, rc: http.NewResponseController(w), flushInterval: flushInterval}
+		stopFlushing := func() {}
+		defer func() { stopFlushing() }()
+
+		for item, err := range handler(ctx, req) {
+			var line []byte
+			if err == nil {
+				line, err = json.Marshal(item)
+			}
+			if err != nil {
+				err = cfg.handlerError(r, err)
+				if !out.started {
+					codec := DefaultCodec[Req, T]{}
+					if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
+						slog.Error("failed to encode error response", "error", encodeErr)
+						http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					}
+					return
+				}
+				if record, marshalErr := json.Marshal(map[string]*Problem{"$error": problemFor(err)}); marshalErr == nil {
+					_ = out.writeLine(record)
+				}
+				break
+			}
+
+			if !out.started {
+				out.start()
+				stopFlushing = startTicker(ctx, flushInterval, func() {
+					if out.flush() != nil {
+						cancel()
+					}
+				})
+			}
+			if err := out.writeLine(line); err != nil {
+				return // the client is gone; breaking stops the sequence
+			}
+		}
+		stopFlushing()
+		if !out.started {
+			out.start()
+		}
+		_ = out.flush()
+	})
+}
+
+// ndjsonWriter writes lines to a response, flushing them after every line or
+// periodically from another goroutine.
+type ndjsonWriter struct {
+	w             http.ResponseWriter
+	rc            *http.ResponseController
+	flushInterval time.Duration
+	started       bool
+
+	mu    sync.Mutex
+	dirty bool
+}
+
+func (n *ndjsonWriter) start() {
+	n.started = true
+	// Streams outlive the server's WriteTimeout.
+	_ = n.rc.SetWriteDeadline(time.Time{})
+	n.w.Header().Set("Content-Type", NDJSONContentType)
+	n.w.Header().Set("X-Accel-Buffering", "no")
+	n.w.WriteHeader(http.StatusOK)
+}
+
+func (n *ndjsonWriter) writeLine(line []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("ndjson: write item: %w", err)
+	}
+	if n.flushInterval <= 0 {
+		return n.rc.Flush() //nolint:wrapcheck // flush errors only mean the client is gone
+	}
+	n.dirty = true
+	return nil
+}
+
+func (n *ndjsonWriter) flush() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.dirty && n.flushInterval > 0 {
+		return nil
+	}
+	n.dirty = false
+	return n.rc.Flush() //nolint:wrapcheck // flush errors only mean the client is gone
+}
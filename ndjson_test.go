@@ -0,0 +1,190 @@
+package httprpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type listRequest struct {
+	Prefix string `json:"prefix" query:"prefix" validate:"required"`
+	Count  int    `json:"count" query:"count"`
+	FailAt int    `json:"fail_at" query:"fail_at"`
+}
+
+type listItem struct {
+	Name string `json:"name"`
+}
+
+var errListing = errors.New("listing failed")
+
+func listHandler(_ context.Context, req listRequest) iter.Seq2[listItem, error] {
+	return func(yield func(listItem, error) bool) {
+		for i := 1; i <= req.Count; i++ {
+			if i == req.FailAt {
+				yield(listItem{}, errListing)
+				return
+			}
+			if !yield(listItem{Name: req.Prefix + string(rune('0'+i))}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func newNDJSONHandler(t *testing.T, handler NDJSONHandler[listRequest, listItem], opts ...StreamOption) http.Handler {
+	t.Helper()
+	r := New(WithRedactedErrors())
+	RegisterNDJSON(r.EndpointGroup, NDJSON(handler, "/items"), opts...)
+	r.MapError(errListing, http.StatusServiceUnavailable, "listing_failed")
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	return h
+}
+
+func TestNDJSON_StreamsItems(t *testing.T) {
+	h := newNDJSONHandler(t, listHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?prefix=a&count=3", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != NDJSONContentType {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	want := "{\"name\":\"a1\"}\n{\"name\":\"a2\"}\n{\"name\":\"a3\"}\n"
+	if rec.Body.String() != want || !rec.Flushed {
+		t.Fatalf("unexpected body %q (flushed %v)", rec.Body.String(), rec.Flushed)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?prefix=a", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("expected an empty stream, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNDJSON_Errors(t *testing.T) {
+	h := newNDJSONHandler(t, listHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?count=1", nil))
+	if rec.Code != http.StatusUnprocessableEntity || rec.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatalf("expected a problem response for an invalid request, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?prefix=a&count=3&fail_at=1", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"code":"listing_failed"`) {
+		t.Fatalf("expected an error response before the first item, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?prefix=a&count=3&fail_at=2", nil))
+	want := "{\"name\":\"a1\"}\n{\"$error\":{\"title\":\"Service Unavailable\",\"status\":503,\"detail\":\"listing failed\",\"code\":\"listing_failed\"}}\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("unexpected body:\n%q\nwant\n%q", rec.Body.String(), want)
+	}
+}
+
+func TestNDJSON_NoAutoHead(t *testing.T) {
+	called := false
+	h := newNDJSONHandler(t, func(context.Context, listRequest) iter.Seq2[listItem, error] {
+		called = true
+		return func(func(listItem, error) bool) {}
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/items?prefix=a", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, OPTIONS" {
+		t.Fatalf("expected 405 for HEAD on a stream, got %d (Allow %q)", rec.Code, rec.Header().Get("Allow"))
+	}
+	if called {
+		t.Fatal("expected HEAD not to run the stream handler")
+	}
+}
+
+func TestNDJSON_FlushesWhileStreaming(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	h := newNDJSONHandler(t, func(ctx context.Context, _ listRequest) iter.Seq2[listItem, error] {
+		return func(yield func(listItem, error) bool) {
+			if !yield(listItem{Name: "first"}, nil) {
+				return
+			}
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+		}
+	}, WithFlushInterval(10*time.Millisecond))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/items?prefix=a")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil || line != "{\"name\":\"first\"}\n" {
+		t.Fatalf("expected the first item before the stream ended, got %q, %v", line, err)
+	}
+}
+
+func TestRouterGenTS_NDJSON(t *testing.T) {
+	r := New()
+	RegisterNDJSON(r.EndpointGroup, NDJSON(listHandler, "/items"))
+	RegisterNDJSON(r.EndpointGroup, NDJSONEndpoint[listRequest, listItem]{Handler: listHandler, Path: "/items/search", Method: http.MethodPost})
+
+	desc := r.Describe()
+	if len(desc) != 2 || desc[0].Stream != StreamNDJSON || desc[0].Res.Name != "listItem" {
+		t.Fatalf("unexpected description %+v", desc)
+	}
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"req?: listRequest,\n    options?: StreamOptions,\n  ): AsyncGenerator<listItem, void, undefined> {",
+		`return this.ndjson<listItem>("GET", "/items", undefined, req, undefined, options)`,
+		`return this.ndjson<listItem>("POST", "/items/search", req, undefined, undefined, options)`,
+		"private async *ndjson<TItem>(",
+		"async function* readLines(",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "readEvents") {
+		t.Fatalf("expected no server-sent event helpers in generated client:\n%s", out)
+	}
+
+	dir := t.TempDir()
+	if err := r.GenTSDir(dir, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTSDir error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Clean(filepath.Join(dir, "items.ts")))
+	if err != nil {
+		t.Fatalf("read module: %v", err)
+	}
+	module := string(data)
+	for _, want := range []string{
+		"import type { ClientOptions, StreamOptions } from './base'\nimport { ndjson } from './base'\n",
+		`return ndjson<listItem>(this.opts, "GET", "/items", undefined, req, undefined, options)`,
+	} {
+		if !strings.Contains(module, want) {
+			t.Fatalf("expected %q in module:\n%s", want, module)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package httprpc
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WithCodecs registers several codecs for one endpoint. Each request is
+// decoded by the codec that consumes its Content-Type and encoded by the
+// codec whose content type ranks highest in its Accept header. Requests
+// without those headers use the first codec. Unmatched requests get 415
+// Unsupported Media Type or 406 Not Acceptable.
+func WithCodecs[Req, Res any](codecs ...Codec[Req, Res]) RegisterOption[Req, Res] {
+	return registerOptionFunc[Req, Res](func(o *registerOptions[Req, Res]) { o.codec = negotiated(codecs) })
+}
+
+// WithCodecsWithMeta registers several codecs for one endpoint with metadata.
+func WithCodecsWithMeta[Req, Meta, Res any](codecs ...Codec[Req, Res]) RegisterOptionWithMeta[Req, Meta, Res] {
+	return registerOptionWithMetaFunc[Req, Meta, Res](func(o *registerOptionsWithMeta[Req, Meta, Res]) {
+		o.codec = negotiated(codecs)
+	})
+}
+
+func negotiated[Req, Res any](codecs []Codec[Req, Res]) Codec[Req, Res] {
+	codecs = slices.DeleteFunc(slices.Clone(codecs), func(c Codec[Req, Res]) bool { return c == nil })
+	if len(codecs) == 0 {
+		return DefaultCodec[Req, Res]{}
+	}
+	if len(codecs) == 1 {
+		return codecs[0]
+	}
+	return negotiatingCodec[Req, Res]{codecs: codecs}
+}
+
+// requestCodec is implemented by codecs that pick a concrete codec per request.
+type requestCodec[Req, Res any] interface {
+	negotiate(r *http.Request) (Codec[Req, Res], error)
+}
+
+// negotiatingCodec selects among codecs by Content-Type and Accept. Used
+// directly, it behaves like its first codec.
+type negotiatingCodec[Req, Res any] struct {
+	codecs []Codec[Req, Res]
+}
+
+func (c negotiatingCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
+	return c.codecs[0].DecodeBody(r) //nolint:wrapcheck // delegated codec error
+}
+
+func (c negotiatingCodec[Req, Res]) DecodeQuery(r *http.Request) (Req, error) {
+	return c.codecs[0].DecodeQuery(r) //nolint:wrapcheck // delegated codec error
+}
+
+func (c negotiatingCodec[Req, Res]) Encode(w http.ResponseWriter, res Res) error {
+	return c.codecs[0].Encode(w, res) //nolint:wrapcheck // delegated codec error
+}
+
+func (c negotiatingCodec[Req, Res]) EncodeError(w http.ResponseWriter, err error) error {
+	return c.codecs[0].EncodeError(w, err) //nolint:wrapcheck // delegated codec error
+}
+
+// Consumes returns the content types of all codecs, in registration order.
+func (c negotiatingCodec[Req, Res]) Consumes() []string {
+	var out []string
+	for _, codec := range c.codecs {
+		for _, ct := range codecConsumes(codec) {
+			if !slices.Contains(out, ct) {
+				out = append(out, ct)
+			}
+		}
+	}
+	return out
+}
+
+// Produces returns the content types of all codecs, in registration order.
+func (c negotiatingCodec[Req, Res]) Produces() []string {
+	var out []string
+	for _, codec := range c.codecs {
+		for _, ct := range codecProduces(codec) {
+			if !slices.Contains(out, ct) {
+				out = append(out, ct)
+			}
+		}
+	}
+	return out
+}
+
+// negotiate returns a codec that decodes with the codec matching r's
+// Content-Type and encodes with the one preferred by r's Accept header. The
+// error is a StatusError (406 or 415) to be encoded with the returned codec.
+func (c negotiatingCodec[Req, Res]) negotiate(r *http.Request) (Codec[Req, Res], error) {
+	enc, ok := c.encoder(r.Header.Values("Accept"))
+	if !ok {
+		return c.codecs[0], StatusError{
+			Status: http.StatusNotAcceptable,
+			Err:    fmt.Errorf("no acceptable content type; available: %s", strings.Join(c.Produces(), ", ")),
+		}
+	}
+	dec := 0
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if dec, ok = c.decoder(r.Header.Get("Content-Type")); !ok {
+			return c.codecs[enc], StatusError{
+				Status: http.StatusUnsupportedMediaType,
+				Err:    fmt.Errorf("unsupported content type %q; supported: %s", r.Header.Get("Content-Type"), strings.Join(c.Consumes(), ", ")),
+			}
+		}
+	}
+	if dec == enc {
+		return c.codecs[dec], nil
+	}
+	return splitCodec[Req, Res]{dec: c.codecs[dec], enc: c.codecs[enc]}, nil
+}
+
+// decoder returns the index of the codec consuming contentType.
+func (c negotiatingCodec[Req, Res]) decoder(contentType string) (int, bool) {
+	if contentType == "" {
+		return 0, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, false
+	}
+	for i, codec := range c.codecs {
+		if slices.Contains(codecConsumes(codec), mediaType) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// encoder returns the index of the codec producing the content type the
+// Accept header ranks highest; earlier codecs win ties.
+func (c negotiatingCodec[Req, Res]) encoder(accept []string) (int, bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return 0, true
+	}
+	best, bestQ := -1, 0.0
+	for i, codec := range c.codecs {
+		for _, ct := range codecProduces(codec) {
+			if q := acceptQuality(ranges, ct); q > bestQ {
+				best, bestQ = i, q
+			}
+		}
+	}
+	return best, best >= 0
+}
+
+// codecForRequest returns the codec handling r: codec itself, or the one it
+// negotiates for r.
+func codecForRequest[Req, Res any](codec Codec[Req, Res], r *http.Request) (Codec[Req, Res], error) {
+	if n, ok := codec.(requestCodec[Req, Res]); ok {
+		return n.negotiate(r)
+	}
+	return codec, nil
+}
+
+// splitCodec decodes with one codec and encodes with another.
+type splitCodec[Req, Res any] struct {
+	dec Codec[Req, Res]
+	enc Codec[Req, Res]
+}
+
+func (c splitCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
+	return c.dec.DecodeBody(r) //nolint:wrapcheck // delegated codec error
+}
+
+func (c splitCodec[Req, Res]) DecodeQuery(r *http.Request) (Req, error) {
+	return c.dec.DecodeQuery(r) //nolint:wrapcheck // delegated codec error
+}
+
+func (c splitCodec[Req, Res]) Encode(w http.ResponseWriter, res Res) error {
+	return c.enc.Encode(w, res) //nolint:wrapcheck // delegated codec error
+}
+
+func (c splitCodec[Req, Res]) EncodeError(w http.ResponseWriter, err error) error {
+	return c.enc.EncodeError(w, err) //nolint:wrapcheck // delegated codec error
+}
+
+// codecConsumes returns the content types codec decodes. Codecs that don't
+// say are assumed to handle JSON.
+func codecConsumes(codec any) []string {
+	if c, ok := codec.(interface{ Consumes() []string }); ok {
+		return c.Consumes()
+	}
+	return []string{"application/json"}
+}
+
+// codecProduces returns the content types codec encodes. Codecs that don't
+// say are assumed to produce JSON.
+func codecProduces(codec any) []string {
+	if c, ok := codec.(interface{ Produces() []string }); ok {
+		return c.Produces()
+	}
+	return []string{"application/json"}
+}
+
+type acceptRange struct {
+	typ, sub string
+	q        float64
+}
+
+// parseAccept parses Accept header values into media ranges. Malformed
+// entries are ignored.
+func parseAccept(values []string) []acceptRange {
+	var out []acceptRange
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			typ, sub, ok := strings.Cut(mediaType, "/")
+			if !ok {
+				continue
+			}
+			q := 1.0
+			if qs, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(qs, 64); err == nil && parsed >= 0 && parsed <= 1 {
+					q = parsed
+				}
+			}
+			out = append(out, acceptRange{typ: typ, sub: sub, q: q})
+		}
+	}
+	return out
+}
+
+// acceptQuality returns the quality the most specific matching range gives
+// contentType, or 0 if no range matches.
+func acceptQuality(ranges []acceptRange, contentType string) float64 {
+	typ, sub, _ := strings.Cut(contentType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.typ == typ && r.sub == sub:
+			s = 2
+		case r.typ == typ && r.sub == "*":
+			s = 1
+		case r.typ == "*" && r.sub == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
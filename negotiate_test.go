@@ -0,0 +1,168 @@
+package httprpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type csvItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// csvCodec reads and writes a single "name,count" line.
+type csvCodec[Req, Res any] struct {
+	DefaultCodec[Req, Res]
+}
+
+func (csvCodec[Req, Res]) Consumes() []string { return []string{"text/csv"} }
+func (csvCodec[Req, Res]) Produces() []string { return []string{"text/csv"} }
+
+func (csvCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
+	var req Req
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return req, err
+	}
+	item, ok := any(&req).(*csvItem)
+	if !ok {
+		return req, fmt.Errorf("csv: unsupported type %T", req)
+	}
+	if _, err := fmt.Sscanf(strings.ReplaceAll(strings.TrimSpace(string(data)), ",", " "), "%s %d", &item.Name, &item.Count); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+func (csvCodec[Req, Res]) Encode(w http.ResponseWriter, res Res) error {
+	item, ok := any(res).(csvItem)
+	if !ok {
+		return fmt.Errorf("csv: unsupported type %T", res)
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	_, err := fmt.Fprintf(w, "%s,%d\n", item.Name, item.Count)
+	return err
+}
+
+func newNegotiatingRouter(t *testing.T) http.Handler {
+	t.Helper()
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(_ context.Context, in csvItem) (csvItem, error) {
+		in.Count++
+		return in, nil
+	}, "/items"), WithCodecs[csvItem, csvItem](DefaultCodec[csvItem, csvItem]{}, csvCodec[csvItem, csvItem]{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	return h
+}
+
+func TestWithCodecs_NegotiatesContentTypeAndAccept(t *testing.T) {
+	h := newNegotiatingRouter(t)
+
+	cases := []struct {
+		name        string
+		contentType string
+		accept      string
+		body        string
+		wantType    string
+		wantBody    string
+	}{
+		{"defaults to first codec", "", "", `{"name":"a","count":1}`, "application/json", `{"name":"a","count":2}`},
+		{"json in json out", "application/json", "application/json", `{"name":"a","count":1}`, "application/json", `{"name":"a","count":2}`},
+		{"csv in csv out", "text/csv; charset=utf-8", "text/csv", "a,1", "text/csv", "a,2\n"},
+		{"csv in json out", "text/csv", "application/json", "a,1", "application/json", `{"name":"a","count":2}`},
+		{"q-values pick csv", "application/json", "application/json;q=0.5, text/csv", `{"name":"a","count":1}`, "text/csv", "a,2\n"},
+		{"specific range beats wildcard", "application/json", "text/*;q=0.1, */*", `{"name":"a","count":1}`, "application/json", `{"name":"a","count":2}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tc.wantType) {
+				t.Fatalf("expected content type %q, got %q", tc.wantType, got)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != strings.TrimSpace(tc.wantBody) {
+				t.Fatalf("expected body %q, got %q", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestWithCodecs_RejectsUnsupportedTypes(t *testing.T) {
+	h := newNegotiatingRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader("<item/>"))
+	req.Header.Set("Content-Type", "application/xml")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnsupportedMediaType, rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name":"a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/xml, text/csv;q=0")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotAcceptable, rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "application/json, text/csv") {
+		t.Fatalf("expected available types in error, got %s", rec.Body.String())
+	}
+}
+
+func TestWithCodecs_DescribeAndTSListEveryType(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(_ context.Context, in csvItem) (csvItem, error) {
+		return in, nil
+	}, "/items"), WithCodecs[csvItem, csvItem](csvCodec[csvItem, csvItem]{}, DefaultCodec[csvItem, csvItem]{}))
+
+	desc := r.Describe()
+	if len(desc) != 1 {
+		t.Fatalf("expected 1 endpoint, got %d", len(desc))
+	}
+	want := []string{"text/csv", "application/json"}
+	if !slices.Equal(desc[0].Consumes, want) || !slices.Equal(desc[0].Produces, want) {
+		t.Fatalf("expected consumes/produces %v, got %v / %v", want, desc[0].Consumes, desc[0].Produces)
+	}
+
+	outDir := t.TempDir()
+	if err := r.GenTSDir(outDir, TSGenOptions{PackageName: "httprpc-test"}); err != nil {
+		t.Fatalf("GenTSDir error: %v", err)
+	}
+	mod, err := os.ReadFile(filepath.Clean(filepath.Join(outDir, "items.ts")))
+	if err != nil {
+		t.Fatalf("read items.ts: %v", err)
+	}
+	for _, s := range []string{
+		"/** Consumes: text/csv, application/json. Produces: text/csv, application/json. */",
+		`'Content-Type': "application/json"`,
+		`'Accept': "application/json"`,
+	} {
+		if !strings.Contains(string(mod), s) {
+			t.Fatalf("expected %q in generated module:\n%s", s, mod)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package httprpc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const uuidLen = 36
+
+// paramConstraint restricts the values a path param segment accepts.
+// Routes with the same shape but different constraints can coexist; the
+// router tries constrained params before unconstrained ones.
+type paramConstraint struct {
+	name  string // "int", "uint", "uuid" or a regular expression
+	match func(string) bool
+}
+
+func parseParamConstraint(spec string) (*paramConstraint, error) {
+	switch spec {
+	case "":
+		return nil, fmt.Errorf("empty constraint")
+	case "int":
+		return &paramConstraint{name: spec, match: isIntSegment}, nil
+	case "uint":
+		return &paramConstraint{name: spec, match: isUintSegment}, nil
+	case "uuid":
+		return &paramConstraint{name: spec, match: isUUIDSegment}, nil
+	default:
+		re, err := regexp.Compile("^(?:" + spec + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", spec, err)
+		}
+		return &paramConstraint{name: spec, match: re.MatchString}, nil
+	}
+}
+
+func (c *paramConstraint) key() string {
+	if c == nil {
+		return ""
+	}
+	return c.name
+}
+
+func (c *paramConstraint) accepts(s string) bool {
+	return c == nil || c.match(s)
+}
+
+// tsType returns the TypeScript type accepted for a param with this constraint.
+func (c *paramConstraint) tsType() string {
+	if c == nil {
+		return "string | number"
+	}
+	switch c.name {
+	case "int", "uint":
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func isIntSegment(s string) bool {
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	}
+	return isUintSegment(s)
+}
+
+func isUintSegment(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUIDSegment(s string) bool {
+	if len(s) != uuidLen {
+		return false
+	}
+	for i := range len(s) {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// stripParamConstraints removes "<...>" constraint specs from a route path.
+func stripParamConstraints(path string) string {
+	if !strings.Contains(path, "<") {
+		return path
+	}
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, ":") {
+			continue
+		}
+		open, closing := strings.IndexByte(part, '<'), strings.LastIndexByte(part, '>')
+		if open < 0 || closing < open {
+			continue
+		}
+		parts[i] = part[:open] + part[closing+1:]
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,181 @@
+package httprpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// ProblemContentType is the media type of RFC 9457 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. The built-in codecs encode
+// every error as a Problem; handlers may return a *Problem to set the type
+// URI, instance or extension members themselves.
+type Problem struct {
+	Type     string // URI reference identifying the problem type; "about:blank" if empty
+	Title    string // short summary of the problem type; the status text if empty
+	Status   int    // HTTP status code; 500 if zero
+	Detail   string // explanation specific to this occurrence
+	Instance string // URI reference identifying this occurrence
+
+	// Extensions are encoded as additional top-level members. The built-in
+	// codecs use "code", "details" and "violations".
+	Extensions map[string]any
+}
+
+func (p *Problem) Error() string {
+	switch {
+	case p.Detail != "":
+		return p.Detail
+	case p.Title != "":
+		return p.Title
+	default:
+		return http.StatusText(p.status())
+	}
+}
+
+func (p *Problem) status() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+
+// problemMembers are the members defined by RFC 9457; extensions cannot
+// override them.
+var problemMembers = []string{"type", "title", "status", "detail", "instance"}
+
+// MarshalJSON encodes the standard members followed by the extensions in
+// key order.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	write := func(key string, value any) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("marshal problem member %q: %w", key, err)
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(data)
+		return nil
+	}
+	if p.Type != "" {
+		_ = write("type", p.Type)
+	}
+	if p.Title != "" {
+		_ = write("title", p.Title)
+	}
+	if p.Status != 0 {
+		_ = write("status", p.Status)
+	}
+	if p.Detail != "" {
+		_ = write("detail", p.Detail)
+	}
+	if p.Instance != "" {
+		_ = write("instance", p.Instance)
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.Extensions)) {
+		if slices.Contains(problemMembers, key) {
+			continue
+		}
+		if err := write(key, p.Extensions[key]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a problem, collecting unknown members into Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return fmt.Errorf("unmarshal problem: %w", err)
+	}
+	*p = Problem{}
+	fields := map[string]any{"type": &p.Type, "title": &p.Title, "status": &p.Status, "detail": &p.Detail, "instance": &p.Instance}
+	for key, raw := range members {
+		if dst, ok := fields[key]; ok {
+			if err := json.Unmarshal(raw, dst); err != nil {
+				return fmt.Errorf("unmarshal problem member %q: %w", key, err)
+			}
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return fmt.Errorf("unmarshal problem member %q: %w", key, err)
+		}
+		if p.Extensions == nil {
+			p.Extensions = map[string]any{}
+		}
+		p.Extensions[key] = v
+	}
+	return nil
+}
+
+// problemFor builds the problem the built-in codecs encode for err:
+//   - a *Problem is used as is, with its status and title filled in;
+//   - a StatusError sets the status and the "code" and "details" members;
+//   - a ValidationError sets 422 (unless a StatusError says otherwise) and the
+//     "violations" member;
+//   - anything else is a 500.
+//
+// The detail is err's message.
+func problemFor(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) && p != nil {
+		out := *p
+		out.Status = p.status()
+		if out.Title == "" {
+			out.Title = http.StatusText(out.Status)
+		}
+		return &out
+	}
+
+	out := &Problem{Status: http.StatusInternalServerError, Detail: err.Error()}
+	var ve ValidationError
+	if errors.As(err, &ve) {
+		out.Status = http.StatusUnprocessableEntity
+		out.extend("violations", ve.Violations)
+	}
+	var se StatusError
+	if errors.As(err, &se) {
+		if se.Status != 0 {
+			out.Status = se.Status
+		}
+		if se.Code != "" {
+			out.extend("code", se.Code)
+		}
+		if se.Details != nil {
+			out.extend("details", se.Details)
+		}
+	}
+	out.Title = http.StatusText(out.Status)
+	return out
+}
+
+func (p *Problem) extend(key string, value any) {
+	if p.Extensions == nil {
+		p.Extensions = map[string]any{}
+	}
+	p.Extensions[key] = value
+}
+
+// isPublicError reports whether err's message is meant for clients: it
+// carries a status, a problem or validation violations.
+func isPublicError(err error) bool {
+	var se StatusError
+	var p *Problem
+	var ve ValidationError
+	return errors.As(err, &se) || errors.As(err, &p) || errors.As(err, &ve)
+}
@@ -0,0 +1,151 @@
+package httprpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type quotaDetails struct {
+	Limit int `json:"limit"`
+	Used  int `json:"used"`
+}
+
+func TestDefaultCodecEncodeError_WritesProblemDetails(t *testing.T) {
+	codec := DefaultCodec[struct{}, struct{}]{}
+	rec := httptest.NewRecorder()
+
+	_ = codec.EncodeError(rec, StatusError{
+		Status:  http.StatusTooManyRequests,
+		Err:     errors.New("quota exceeded"),
+		Code:    "quota_exceeded",
+		Details: quotaDetails{Limit: 10, Used: 10},
+	})
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("expected content type %q, got %q", ProblemContentType, ct)
+	}
+	want := `{"title":"Too Many Requests","status":429,"detail":"quota exceeded","code":"quota_exceeded","details":{"limit":10,"used":10}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("unexpected body:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestDefaultCodecEncodeError_ProblemIsUsedAsIs(t *testing.T) {
+	codec := DefaultCodec[struct{}, struct{}]{}
+	rec := httptest.NewRecorder()
+
+	_ = codec.EncodeError(rec, &Problem{
+		Type:       "https://example.com/probs/out-of-credit",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Instance:   "/account/12345/msgs/abc",
+		Extensions: map[string]any{"balance": 30, "status": "ignored"},
+	})
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	var got Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	want := Problem{
+		Type:       "https://example.com/probs/out-of-credit",
+		Title:      "Forbidden",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Instance:   "/account/12345/msgs/abc",
+		Extensions: map[string]any{"balance": float64(30)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected problem:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestWithRedactedErrors(t *testing.T) {
+	handler := func(_ context.Context, in struct {
+		Kind string `json:"kind"`
+	},
+	) (struct{}, error) {
+		switch in.Kind {
+		case "status":
+			return struct{}{}, StatusError{Status: http.StatusNotFound, Err: errors.New("user 7 not found")}
+		case "validation":
+			return struct{}{}, ValidationError{Violations: []Violation{{Path: "kind", Code: "invalid", Message: "bad kind"}}}
+		default:
+			return struct{}{}, errors.New("pq: connection refused to 10.0.0.5")
+		}
+	}
+
+	for _, redact := range []bool{false, true} {
+		var opts []RouterOption
+		if redact {
+			opts = append(opts, WithRedactedErrors())
+		}
+		r := New(opts...)
+		RegisterHandler(r.EndpointGroup, POST(handler, "/do"))
+		h, err := r.Handler()
+		if err != nil {
+			t.Fatalf("handler build error: %v", err)
+		}
+
+		call := func(kind string) (int, Problem) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/do", strings.NewReader(`{"kind":"`+kind+`"}`)))
+			var p Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			return rec.Code, p
+		}
+
+		code, p := call("internal")
+		if code != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got %d", code)
+		}
+		if leaked := strings.Contains(p.Detail, "10.0.0.5"); leaked == redact {
+			t.Fatalf("redact=%v: unexpected detail %q", redact, p.Detail)
+		}
+
+		if code, p = call("status"); code != http.StatusNotFound || p.Detail != "user 7 not found" {
+			t.Fatalf("redact=%v: StatusError must pass through, got %d %+v", redact, code, p)
+		}
+		if code, p = call("validation"); code != http.StatusUnprocessableEntity || p.Extensions["violations"] == nil {
+			t.Fatalf("redact=%v: ValidationError must pass through, got %d %+v", redact, code, p)
+		}
+	}
+}
+
+func TestRouterGenTS_EmitsApiError(t *testing.T) {
+	r := New()
+	type Violation struct {
+		Field string `json:"field"`
+	}
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, Violation) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/check"))
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"export class ApiError<TDetails = unknown> extends Error {",
+		"throw new ApiError(res.status, await problemFromResponse(res))",
+		"export interface Violation2 {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, out)
+		}
+	}
+}
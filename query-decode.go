@@ -4,7 +4,9 @@ import (
 	"encoding"
 	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,50 +15,137 @@ import (
 func decodeQueryParams[Req any](r *http.Request) (Req, error) {
 	var req Req
 
+	plan := queryPlanFor(reflect.TypeFor[Req]())
 	values := r.URL.Query()
-	if len(values) == 0 {
+	if len(values) == 0 && !plan.hasDefaults {
 		return req, nil
 	}
-
-	rv := reflect.ValueOf(&req).Elem()
-	rt := rv.Type()
-	if rv.Kind() != reflect.Struct {
-		return req, fmt.Errorf("decode query: request type %s must be a struct", rt.Kind())
+	if plan.err != nil {
+		return req, plan.err
+	}
+	if err := decodeValues(reflect.ValueOf(&req).Elem(), plan, values, "query"); err != nil {
+		return req, err
 	}
+	return req, nil
+}
 
-	for i := range rt.NumField() {
-		field := rt.Field(i)
-		if !field.IsExported() {
-			continue
+// decodeValues sets the fields of rv described by plan from values. source
+// names the values in errors ("query" or "form").
+func decodeValues(rv reflect.Value, plan *queryPlan, values url.Values, source string) error {
+	values = normalizeQueryValues(values)
+	for _, f := range plan.fields {
+		vals, ok := values[f.name]
+		if !ok {
+			if !f.rules.hasDefault() {
+				continue
+			}
+			vals = f.rules.def
+		}
+		v := fieldByIndexAlloc(rv, f.index)
+		if err := setFromStrings(v, vals); err != nil {
+			return fmt.Errorf("decode %s %s: %w", source, f.name, err)
+		}
+		if err := f.rules.checkEnum(v); err != nil {
+			return fmt.Errorf("decode %s %s: %w", source, f.name, err)
 		}
-		if field.Anonymous && deref(field.Type).Kind() == reflect.Struct {
-			// Skip embedded structs for now; keep behavior aligned with TS generator.
-			continue
+	}
+	for _, f := range plan.maps {
+		if err := setQueryMap(rv, f, values, source); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		name, skip, err := queryFieldName(rt, field)
-		if err != nil {
-			return req, err
+// normalizeQueryValues rewrites bracketed keys to dotted form:
+// "filter[status]" becomes "filter.status" and "tags[]" becomes "tags".
+func normalizeQueryValues(values url.Values) url.Values {
+	bracketed := false
+	for k := range values {
+		if strings.IndexByte(k, '[') >= 0 {
+			bracketed = true
+			break
 		}
-		if skip {
-			continue
+	}
+	if !bracketed {
+		return values
+	}
+
+	out := make(url.Values, len(values))
+	for k, vals := range values {
+		nk := normalizeQueryKey(k)
+		out[nk] = append(out[nk], vals...)
+	}
+	return out
+}
+
+func normalizeQueryKey(k string) string {
+	var b strings.Builder
+	b.Grow(len(k))
+	for i := 0; i < len(k); i++ {
+		switch c := k[i]; c {
+		case '[':
+			if i+1 < len(k) && k[i+1] == ']' {
+				i++
+				continue
+			}
+			b.WriteByte('.')
+		case ']':
+		default:
+			b.WriteByte(c)
 		}
+	}
+	return b.String()
+}
 
-		vals, ok := values[name]
-		if !ok {
-			continue
+// setQueryMap fills a map[string]T field from the "name.<key>" query values.
+func setQueryMap(rv reflect.Value, f queryField, values url.Values, source string) error {
+	prefix := f.name + "."
+	var keys []string
+	for k := range values {
+		if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+			keys = append(keys, k)
 		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
 
-		fv := rv.Field(i)
-		if !fv.CanSet() {
-			continue
+	mv := fieldByIndexAlloc(rv, f.index)
+	if mv.Kind() == reflect.Pointer {
+		if mv.IsNil() {
+			mv.Set(reflect.New(mv.Type().Elem()))
 		}
-		if err := setFromStrings(fv, vals); err != nil {
-			return req, fmt.Errorf("decode query %s: %w", name, err)
+		mv = mv.Elem()
+	}
+	if mv.IsNil() {
+		mv.Set(reflect.MakeMapWithSize(mv.Type(), len(keys)))
+	}
+	mt := mv.Type()
+	for _, k := range keys {
+		elem := reflect.New(mt.Elem()).Elem()
+		if err := setFromStrings(elem, values[k]); err != nil {
+			return fmt.Errorf("decode %s %s: %w", source, k, err)
 		}
+		mv.SetMapIndex(reflect.ValueOf(k[len(prefix):]).Convert(mt.Key()), elem)
 	}
+	return nil
+}
 
-	return req, nil
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex but allocates nil
+// embedded or nested struct pointers along the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
 }
 
 func setFromStrings(v reflect.Value, vals []string) error {
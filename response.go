@@ -0,0 +1,84 @@
+package httprpc
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Response lets a handler set the status, headers and cookies of a response
+// while keeping a typed body. Return it as the handler's response type:
+//
+//	func(ctx context.Context, req CreateUserRequest) (httprpc.Response[User], error) {
+//		...
+//		return httprpc.Response[User]{
+//			Body:   user,
+//			Status: http.StatusCreated,
+//			Header: http.Header{"Location": {"/users/" + user.ID}},
+//		}, nil
+//	}
+//
+// The built-in codecs encode Body only, and the TypeScript generator and
+// Describe see T. Headers and cookies are set before the codec runs, so the
+// codec's Content-Type wins.
+type Response[T any] struct {
+	Body T
+	// Status overrides the codec's status. Zero keeps it. The body is not
+	// written for 204 No Content and 304 Not Modified.
+	Status  int
+	Header  http.Header
+	Cookies []*http.Cookie
+}
+
+func (r Response[T]) responseEnvelope() (body any, status int, header http.Header, cookies []*http.Cookie) {
+	return r.Body, r.Status, r.Header, r.Cookies
+}
+
+// envelope is implemented by Response.
+type envelope interface {
+	responseEnvelope() (body any, status int, header http.Header, cookies []*http.Cookie)
+}
+
+var envelopeType = reflect.TypeFor[envelope]()
+
+// unwrapResponse applies the headers and cookies of a Response to w and
+// returns the body to encode and the status to send. For other values it
+// returns res and the codec's status. A nil *Response is sent as a zero
+// Response.
+func unwrapResponse(w http.ResponseWriter, res any, status int) (any, int) {
+	env, ok := res.(envelope)
+	if !ok {
+		return res, status
+	}
+	if rv := reflect.ValueOf(res); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		env, _ = reflect.Zero(rv.Type().Elem()).Interface().(envelope)
+	}
+	body, envStatus, header, cookies := env.responseEnvelope()
+	for key, values := range header {
+		w.Header()[http.CanonicalHeaderKey(key)] = values
+	}
+	for _, c := range cookies {
+		http.SetCookie(w, c)
+	}
+	if envStatus != 0 {
+		status = envStatus
+	}
+	return body, status
+}
+
+// bodyAllowed reports whether a response with status may carry a body.
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// responseBodyType returns T for Response[T] and *Response[T], and t otherwise.
+func responseBodyType(t reflect.Type) reflect.Type {
+	if t != nil && t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct && t.Elem().Implements(envelopeType) {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct && t.Implements(envelopeType) {
+		if f, ok := t.FieldByName("Body"); ok {
+			return f.Type
+		}
+	}
+	return t
+}
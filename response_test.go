@@ -0,0 +1,161 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type createdUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type createUserRequest struct {
+	Name string `json:"name"`
+}
+
+func createUserHandler(_ context.Context, in createUserRequest) (Response[createdUser], error) {
+	if in.Name == "" {
+		return Response[createdUser]{Status: http.StatusNoContent}, nil
+	}
+	return Response[createdUser]{
+		Body:    createdUser{ID: "u1", Name: in.Name},
+		Status:  http.StatusCreated,
+		Header:  http.Header{"location": {"/users/u1"}, "Cache-Control": {"no-store"}},
+		Cookies: []*http.Cookie{{Name: "session", Value: "abc", Path: "/", HttpOnly: true}},
+	}, nil
+}
+
+func TestResponse_DefaultCodec(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(createUserHandler, "/users"))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Ada"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"id":"u1","name":"Ada"}` {
+		t.Fatalf("unexpected body %s", got)
+	}
+	for key, want := range map[string]string{
+		"Location":      "/users/u1",
+		"Cache-Control": "no-store",
+		"Set-Cookie":    "session=abc; Path=/; HttpOnly",
+		"Content-Type":  "application/json",
+	} {
+		if got := rec.Header().Get(key); got != want {
+			t.Fatalf("%s: expected %q, got %q", key, want, got)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("expected empty 204, got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "" {
+		t.Fatalf("expected no Content-Type on 204, got %q", got)
+	}
+}
+
+func TestResponse_KeepsCodecStatus(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (Response[createdUser], error) {
+		return Response[createdUser]{Body: createdUser{ID: "u1"}}, nil
+	}, "/user"), WithCodec[struct{}, Response[createdUser]](DefaultCodec[struct{}, Response[createdUser]]{Status: http.StatusAccepted}))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+}
+
+func TestResponse_BinaryCodec(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(createUserHandler, "/users"),
+		WithCodecs[createUserRequest, Response[createdUser]](
+			DefaultCodec[createUserRequest, Response[createdUser]]{},
+			MsgpackCodec[createUserRequest, Response[createdUser]]{},
+		))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Ada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/msgpack")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/users/u1" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	var got createdUser
+	if err := msgpackFormat.unmarshal(rec.Body.Bytes(), &got, DefaultMaxJSONDepth); err != nil {
+		t.Fatalf("decode msgpack: %v", err)
+	}
+	if got != (createdUser{ID: "u1", Name: "Ada"}) {
+		t.Fatalf("unexpected body %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/msgpack")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+		t.Fatalf("expected empty 204 without Content-Type, got %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestResponse_NilPointer(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (*Response[createdUser], error) {
+		return nil, nil
+	}, "/user"))
+	if desc := r.Describe(); len(desc) != 1 || desc[0].Res.Name != "createdUser" {
+		t.Fatalf("expected Res createdUser, got %+v", desc)
+	}
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user", nil))
+	if got := strings.TrimSpace(rec.Body.String()); rec.Code != http.StatusOK || got != `{"id":"","name":""}` {
+		t.Fatalf("expected a zero body, got %d %s", rec.Code, got)
+	}
+}
+
+func TestResponse_DescribeAndTSSeeBody(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(createUserHandler, "/users"))
+
+	desc := r.Describe()
+	if len(desc) != 1 || desc[0].Res.Name != "createdUser" {
+		t.Fatalf("expected Res createdUser, got %+v", desc)
+	}
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Promise<createdUser>") || strings.Contains(out, "Cookies") {
+		t.Fatalf("expected the body type in generated client:\n%s", out)
+	}
+}
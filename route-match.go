@@ -0,0 +1,221 @@
+package httprpc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// groupMatch holds the request matchers a group adds on top of its prefix.
+// Matchers are inherited by subgroups and evaluated before path matching.
+type groupMatch struct {
+	host    *hostPattern
+	headers []headerMatch
+	// err poisons the group and its subgroups: their endpoints are never added.
+	err error
+}
+
+type headerMatch struct {
+	name  string
+	value string
+}
+
+// Host returns a subgroup whose endpoints only match requests for the given host.
+// Labels starting with ':' capture a param, e.g. ":tenant.example.com".
+// Host params are exposed alongside path params (PathParam and `path` meta tags).
+// The port, if any, is ignored.
+func (eg *EndpointGroup) Host(pattern string) *EndpointGroup {
+	g := eg.Group("")
+	m := g.match.clone()
+	hp, err := parseHostPattern(pattern)
+	if err != nil {
+		m.err = fmt.Errorf("invalid host pattern %q: %w", pattern, err)
+		eg.registerError(m.err)
+	}
+	m.host = hp
+	g.match = m
+	return g
+}
+
+// Header returns a subgroup whose endpoints only match requests carrying the
+// header with exactly the given value, e.g. Header("Accept-Version", "2").
+func (eg *EndpointGroup) Header(name, value string) *EndpointGroup {
+	g := eg.Group("")
+	m := g.match.clone()
+	name = http.CanonicalHeaderKey(name)
+	filtered := m.headers[:0]
+	for _, h := range m.headers {
+		if h.name != name {
+			filtered = append(filtered, h)
+		}
+	}
+	m.headers = append(filtered, headerMatch{name: name, value: value})
+	sort.Slice(m.headers, func(i, j int) bool { return m.headers[i].name < m.headers[j].name })
+	g.match = m
+	return g
+}
+
+func (m *groupMatch) clone() *groupMatch {
+	if m == nil {
+		return &groupMatch{}
+	}
+	return &groupMatch{
+		host:    m.host,
+		headers: append([]headerMatch(nil), m.headers...),
+		err:     m.err,
+	}
+}
+
+// key identifies the set of matchers; endpoints with equal keys share a route tree.
+func (m *groupMatch) key() string {
+	if m == nil {
+		return ""
+	}
+	var b strings.Builder
+	if m.host != nil {
+		b.WriteString(m.host.raw)
+	}
+	for _, h := range m.headers {
+		b.WriteString("\x00")
+		b.WriteString(h.name)
+		b.WriteString("=")
+		b.WriteString(h.value)
+	}
+	return b.String()
+}
+
+// specificity orders route trees: host matchers first, then more headers.
+func (m *groupMatch) specificity() int {
+	if m == nil {
+		return 0
+	}
+	n := len(m.headers)
+	if m.host != nil {
+		n += 1 << 16
+	}
+	return n
+}
+
+func (m *groupMatch) hostParams() []string {
+	if m == nil || m.host == nil {
+		return nil
+	}
+	return m.host.params
+}
+
+func (m *groupMatch) hostPattern() string {
+	if m == nil || m.host == nil {
+		return ""
+	}
+	return m.host.raw
+}
+
+func (m *groupMatch) headerMap() map[string]string {
+	if m == nil || len(m.headers) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m.headers))
+	for _, h := range m.headers {
+		out[h.name] = h.value
+	}
+	return out
+}
+
+// matches reports whether the request satisfies the matchers. Host param
+// values are appended to params.
+func (m *groupMatch) matches(r *http.Request, params *pathParams) bool {
+	if m == nil {
+		return true
+	}
+	for _, h := range m.headers {
+		found := false
+		for _, v := range r.Header[h.name] {
+			if v == h.value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if m.host != nil {
+		return m.host.match(r.Host, params)
+	}
+	return true
+}
+
+type hostPattern struct {
+	raw    string
+	labels []routeSegment
+	params []string
+}
+
+func parseHostPattern(pattern string) (*hostPattern, error) {
+	pattern = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(pattern), "."))
+	if pattern == "" {
+		return nil, fmt.Errorf("empty host")
+	}
+	hp := &hostPattern{raw: pattern}
+	seen := map[string]struct{}{}
+	for label := range strings.SplitSeq(pattern, ".") {
+		if label == "" {
+			return nil, fmt.Errorf("empty label")
+		}
+		if !strings.HasPrefix(label, ":") {
+			if strings.ContainsAny(label, ":*?<>{}/") {
+				return nil, fmt.Errorf("invalid label %q: use :name for params", label)
+			}
+			hp.labels = append(hp.labels, routeSegment{kind: segmentStatic, value: label})
+			continue
+		}
+		name := label[1:]
+		if !isSnakeCase(name) {
+			return nil, fmt.Errorf("invalid host param %q: must be snake_case", name)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate host param %q", name)
+		}
+		seen[name] = struct{}{}
+		hp.labels = append(hp.labels, routeSegment{kind: segmentParam, value: name})
+		hp.params = append(hp.params, name)
+	}
+	return hp, nil
+}
+
+func (h *hostPattern) match(host string, params *pathParams) bool {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	start := len(params.values)
+	for i, label := range h.labels {
+		part := host
+		if i < len(h.labels)-1 {
+			dot := strings.IndexByte(host, '.')
+			if dot < 0 {
+				params.values = params.values[:start]
+				return false
+			}
+			part, host = host[:dot], host[dot+1:]
+		} else if strings.IndexByte(host, '.') >= 0 {
+			params.values = params.values[:start]
+			return false
+		}
+		if label.kind == segmentParam {
+			if part == "" {
+				params.values = params.values[:start]
+				return false
+			}
+			params.values = append(params.values, part)
+			continue
+		}
+		if !strings.EqualFold(part, label.value) {
+			params.values = params.values[:start]
+			return false
+		}
+	}
+	return true
+}
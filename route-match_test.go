@@ -0,0 +1,172 @@
+package httprpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterHandler_HostMatch(t *testing.T) {
+	type tenantMeta struct {
+		Tenant string `path:"tenant"`
+		ID     int    `path:"id"`
+	}
+	type tenantRes struct {
+		Tenant string `json:"tenant"`
+		ID     int    `json:"id"`
+	}
+
+	r := New()
+	admin := r.Host("admin.example.com")
+	RegisterHandler[struct{}, int](admin, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusTeapot, nil
+	}, "/status"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusOK, nil
+	}, "/status"), WithCodec[struct{}, int](statusCodec{}))
+
+	tenants := r.Host(":tenant.example.com").Group("/api")
+	RegisterHandlerM[struct{}, tenantMeta, tenantRes](tenants, GETM(func(_ context.Context, _ struct{}, meta tenantMeta) (tenantRes, error) {
+		return tenantRes(meta), nil
+	}, "/items/:id"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{host: "admin.example.com", want: http.StatusTeapot},
+		{host: "ADMIN.example.com:8443", want: http.StatusTeapot},
+		{host: "www.example.com", want: http.StatusOK},
+		{host: "example.com", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/status", http.NoBody)
+		req.Host = tt.host
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.host, tt.want, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/items/7", http.NoBody)
+	req.Host = "acme.example.com"
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, rec.Code)
+	}
+	var got tenantRes
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Tenant != "acme" || got.ID != 7 {
+		t.Fatalf("unexpected response: %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/items/7", http.NoBody)
+	req.Host = "a.b.example.com"
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestRouterHandler_HeaderMatch(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	RegisterHandler[struct{}, int](api.Header("Accept-Version", "2"), GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusAccepted, nil
+	}, "/users"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](api, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusOK, nil
+	}, "/users"), WithCodec[struct{}, int](statusCodec{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	for version, want := range map[string]int{"": http.StatusOK, "1": http.StatusOK, "2": http.StatusAccepted} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/users", http.NoBody)
+		if version != "" {
+			req.Header.Set("Accept-Version", version)
+		}
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("version %q: expected %d, got %d", version, want, rec.Code)
+		}
+	}
+}
+
+func TestRouterHandler_MethodFallsThroughMatchers(t *testing.T) {
+	r := New(WithoutAutoOptions())
+	RegisterHandler[struct{}, int](r.Host("admin.example.com"), GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusTeapot, nil
+	}, "/items"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](r.EndpointGroup, POST(func(context.Context, struct{}) (int, error) {
+		return http.StatusCreated, nil
+	}, "/items"), WithCodec[struct{}, int](statusCodec{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/items", http.NoBody)
+		req.Host = "admin.example.com"
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := serve(http.MethodGet); rec.Code != http.StatusTeapot {
+		t.Fatalf("expected the host route for GET, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost); rec.Code != http.StatusCreated {
+		t.Fatalf("expected POST to fall through to the catch-all group, got %d", rec.Code)
+	}
+	rec := serve(http.MethodDelete)
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD, POST" {
+		t.Fatalf("expected 405 with the methods of both routes, got %d (Allow %q)", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestRouterHandler_HostParamConflictsWithPathParam(t *testing.T) {
+	r := New()
+	RegisterHandler(r.Host(":id.example.com"), GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/users/:id"))
+
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), "also a host param") {
+		t.Fatalf("expected host param conflict error, got %v", err)
+	}
+}
+
+func TestRouterDescribe_GroupMatchers(t *testing.T) {
+	r := New()
+	RegisterHandler(r.Host("admin.example.com").Header("accept-version", "2"), GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/users"))
+
+	desc := r.Describe()
+	if len(desc) != 1 {
+		t.Fatalf("expected 1 description, got %d", len(desc))
+	}
+	if desc[0].Host != "admin.example.com" {
+		t.Fatalf("expected host, got %q", desc[0].Host)
+	}
+	if desc[0].Headers["Accept-Version"] != "2" {
+		t.Fatalf("expected Accept-Version header matcher, got %v", desc[0].Headers)
+	}
+}
@@ -0,0 +1,262 @@
+package httprpc
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const defaultPathParamsCap = 8
+
+// routeNode is a node in the segment trie used for dispatch.
+// Static children are preferred over param children, which are preferred over
+// the catch-all child; lookups backtrack when a branch does not lead to a route.
+// Constrained param children are tried in registration order before the
+// unconstrained one, which is always last.
+type routeNode struct {
+	static     map[string]*routeNode
+	params     []*routeNode
+	catchAll   *routeNode
+	route      *routeEntry
+	constraint *paramConstraint
+}
+
+// routeEntry is a route terminating at a node. A pattern with optional params
+// produces one entry per accepted length, each with its own param names.
+type routeEntry struct {
+	pattern *routePattern
+	params  []string
+	methods *routeMethods
+}
+
+func newRouteNode() *routeNode {
+	return &routeNode{}
+}
+
+// insert adds the pattern to the trie and returns the entries for its path,
+// one for each combination of present optional params. Leading names precede
+// the pattern's params (e.g. host params matched before the path).
+// Two patterns with the same shape but different param names are ambiguous.
+func (n *routeNode) insert(p *routePattern, leading []string) ([]*routeEntry, error) {
+	entries := make([]*routeEntry, 0, len(p.segments)-p.requiredSegments()+1)
+	for cut := p.requiredSegments(); cut <= len(p.segments); cut++ {
+		entry, err := n.insertSegments(p, p.segments[:cut], leading)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (n *routeNode) insertSegments(p *routePattern, segments []routeSegment, leading []string) (*routeEntry, error) {
+	cur := n
+	params := append([]string(nil), leading...)
+	for _, seg := range segments {
+		switch seg.kind {
+		case segmentParam:
+			cur = cur.paramChild(seg.constraint)
+			params = append(params, seg.value)
+		case segmentCatchAll:
+			if cur.catchAll == nil {
+				cur.catchAll = newRouteNode()
+			}
+			cur = cur.catchAll
+			if seg.value != "" {
+				params = append(params, seg.value)
+			}
+		default:
+			if cur.static == nil {
+				cur.static = map[string]*routeNode{}
+			}
+			next := cur.static[seg.value]
+			if next == nil {
+				next = newRouteNode()
+				cur.static[seg.value] = next
+			}
+			cur = next
+		}
+	}
+
+	if cur.route != nil {
+		if cur.route.pattern.path != p.path {
+			return nil, fmt.Errorf("ambiguous route: %s conflicts with %s", p.path, cur.route.pattern.path)
+		}
+		return cur.route, nil
+	}
+	cur.route = &routeEntry{
+		pattern: p,
+		params:  params,
+		methods: &routeMethods{byMethod: map[string]http.Handler{}},
+	}
+	return cur.route, nil
+}
+
+// mountConflict returns a route registered at or below a mounted prefix, if any.
+func (n *routeNode) mountConflict() (mount, conflict *routeEntry) {
+	if n.catchAll != nil && n.catchAll.route != nil && n.catchAll.route.methods.mount != nil {
+		if e := n.firstRoute(); e != nil {
+			return n.catchAll.route, e
+		}
+	}
+	for _, child := range n.static {
+		if m, e := child.mountConflict(); e != nil {
+			return m, e
+		}
+	}
+	for _, child := range n.params {
+		if m, e := child.mountConflict(); e != nil {
+			return m, e
+		}
+	}
+	return nil, nil
+}
+
+// firstRoute returns a route at n or below it, ignoring n's catch-all.
+func (n *routeNode) firstRoute() *routeEntry {
+	if n.route != nil {
+		return n.route
+	}
+	for _, child := range n.static {
+		if e := child.firstRoute(); e != nil {
+			return e
+		}
+	}
+	for _, child := range n.params {
+		if e := child.firstRoute(); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// walk calls fn for the routes at and below n in lookup priority: the node's
+// own route, then static children (by name), param children, the catch-all.
+func (n *routeNode) walk(fn func(*routeEntry)) {
+	if n.route != nil {
+		fn(n.route)
+	}
+	for _, name := range slices.Sorted(maps.Keys(n.static)) {
+		n.static[name].walk(fn)
+	}
+	for _, child := range n.params {
+		child.walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
+	}
+}
+
+func (n *routeNode) paramChild(c *paramConstraint) *routeNode {
+	for _, child := range n.params {
+		if child.constraint.key() == c.key() {
+			return child
+		}
+	}
+	child := newRouteNode()
+	child.constraint = c
+	if c == nil || len(n.params) == 0 || n.params[len(n.params)-1].constraint != nil {
+		n.params = append(n.params, child)
+		return child
+	}
+	// Keep the unconstrained child last.
+	last := n.params[len(n.params)-1]
+	n.params[len(n.params)-1] = child
+	n.params = append(n.params, last)
+	return child
+}
+
+// lookup matches path (without leading/trailing slashes) against the trie.
+// Param values are appended to params in pattern order. It does not allocate
+// when params has enough capacity.
+func (n *routeNode) lookup(path string, params *pathParams) *routeEntry {
+	if path == "" {
+		if n.route != nil {
+			return n.route
+		}
+		if n.catchAll != nil && n.catchAll.route != nil {
+			params.values = append(params.values, "")
+			return n.catchAll.route
+		}
+		return nil
+	}
+
+	seg, rest := path, ""
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		seg, rest = path[:i], path[i+1:]
+	}
+
+	if child := n.static[seg]; child != nil {
+		if e := child.lookup(rest, params); e != nil {
+			return e
+		}
+	}
+	if seg != "" {
+		for _, child := range n.params {
+			if !child.constraint.accepts(seg) {
+				continue
+			}
+			params.values = append(params.values, seg)
+			if e := child.lookup(rest, params); e != nil {
+				return e
+			}
+			params.values = params.values[:len(params.values)-1]
+		}
+	}
+	if n.catchAll != nil && n.catchAll.route != nil {
+		params.values = append(params.values, path)
+		return n.catchAll.route
+	}
+	return nil
+}
+
+// pathParams holds matched path params for a request.
+// Instances are pooled for the lookup and reset once the handler returns;
+// withPathParams gives the request context its own copy.
+type pathParams struct {
+	names  []string
+	values []string
+}
+
+var pathParamsPool = sync.Pool{
+	New: func() any {
+		return &pathParams{values: make([]string, 0, defaultPathParamsCap)}
+	},
+}
+
+func acquirePathParams() *pathParams {
+	p, _ := pathParamsPool.Get().(*pathParams)
+	if p == nil {
+		p = &pathParams{values: make([]string, 0, defaultPathParamsCap)}
+	}
+	return p
+}
+
+func releasePathParams(p *pathParams) {
+	p.names = nil
+	clear(p.values)
+	p.values = p.values[:0]
+	pathParamsPool.Put(p)
+}
+
+func (p *pathParams) get(name string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for i, n := range p.names {
+		if n == name && i < len(p.values) {
+			return p.values[i], true
+		}
+	}
+	return "", false
+}
+
+func (p *pathParams) len() int {
+	if p == nil {
+		return 0
+	}
+	return min(len(p.names), len(p.values))
+}
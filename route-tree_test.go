@@ -0,0 +1,214 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouteTree_LookupParams(t *testing.T) {
+	root := newRouteNode()
+	for _, path := range []string{"/", "/users", "/users/:id", "/users/:id/posts/:post_id", "/users/me"} {
+		p, err := parseRoutePattern(path)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		if _, err := root.insert(p, nil); err != nil {
+			t.Fatalf("insert %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		path   string
+		want   string
+		values []string
+	}{
+		{path: "", want: "/"},
+		{path: "users", want: "/users"},
+		{path: "users/me", want: "/users/me"},
+		{path: "users/42", want: "/users/:id", values: []string{"42"}},
+		{path: "users/42/posts/7", want: "/users/:id/posts/:post_id", values: []string{"42", "7"}},
+		{path: "users/42/posts", want: ""},
+		{path: "users//posts/7", want: ""},
+		{path: "teams", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			params := acquirePathParams()
+			defer releasePathParams(params)
+
+			entry := root.lookup(tt.path, params)
+			if tt.want == "" {
+				if entry != nil {
+					t.Fatalf("expected no match, got %s", entry.pattern.path)
+				}
+				return
+			}
+			if entry == nil || entry.pattern.path != tt.want {
+				t.Fatalf("expected %s, got %+v", tt.want, entry)
+			}
+			if len(params.values) != len(tt.values) {
+				t.Fatalf("expected values %v, got %v", tt.values, params.values)
+			}
+			for i := range tt.values {
+				if params.values[i] != tt.values[i] {
+					t.Fatalf("expected values %v, got %v", tt.values, params.values)
+				}
+			}
+		})
+	}
+}
+
+func TestRouteTree_MissDoesNotAllocate(t *testing.T) {
+	root := newRouteNode()
+	for _, path := range []string{"/users/:id", "/users/:id/posts", "/teams/:id"} {
+		p, err := parseRoutePattern(path)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		if _, err := root.insert(p, nil); err != nil {
+			t.Fatalf("insert %s: %v", path, err)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		params := acquirePathParams()
+		if root.lookup("users/42/comments", params) != nil {
+			t.Fatalf("unexpected match")
+		}
+		releasePathParams(params)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations on miss, got %v", allocs)
+	}
+}
+
+func TestRouterHandler_PathParamsReleasedAfterRequest(t *testing.T) {
+	r := New()
+	var got map[string]string
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(ctx context.Context, _ struct{}) (int, error) {
+		got = PathParams(ctx)
+		return http.StatusOK, nil
+	}, "/orgs/:org/repos/:repo"), WithCodec[struct{}, int](statusCodec{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/orgs/acme/repos/api", http.NoBody)
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got["org"] != "acme" || got["repo"] != "api" {
+		t.Fatalf("unexpected params: %v", got)
+	}
+}
+
+func TestRouterHandler_PathParamsOutliveRequest(t *testing.T) {
+	r := New()
+	var ctxs []context.Context
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(ctx context.Context, _ struct{}) (int, error) {
+		ctxs = append(ctxs, ctx)
+		return http.StatusOK, nil
+	}, "/orgs/:org"), WithCodec[struct{}, int](statusCodec{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	for _, org := range []string{"acme", "globex", "initech"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/"+org, http.NoBody))
+	}
+	for i, want := range []string{"acme", "globex", "initech"} {
+		if org, ok := PathParam(ctxs[i], "org"); !ok || org != want {
+			t.Fatalf("request %d: expected %q after the handler returned, got %q, %v", i, want, org, ok)
+		}
+	}
+}
+
+func TestRouterHandler_ParamConstraints(t *testing.T) {
+	r := New()
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusOK, nil
+	}, "/users/:id<int>"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusAccepted, nil
+	}, "/users/:uuid<uuid>"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusTeapot, nil
+	}, "/users/:slug"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusOK, nil
+	}, "/airports/:code<[A-Z]{3}>"), WithCodec[struct{}, int](statusCodec{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/users/42", want: http.StatusOK},
+		{path: "/users/-7", want: http.StatusOK},
+		{path: "/users/0b5d6f5e-3c1a-4d8e-9f3a-2b1c0d9e8f7a", want: http.StatusAccepted},
+		{path: "/users/alice", want: http.StatusTeapot},
+		{path: "/airports/LHR", want: http.StatusOK},
+		{path: "/airports/lhr", want: http.StatusNotFound},
+		{path: "/airports/LHRX", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestRouterHandler_ParamConstraintsAmbiguous(t *testing.T) {
+	r := New()
+	RegisterHandler[struct{}, struct{}](r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/users/:id<int>"))
+	RegisterHandler[struct{}, struct{}](r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/users/:user_id<int>"))
+
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), "ambiguous route") {
+		t.Fatalf("expected ambiguous route error, got %v", err)
+	}
+}
+
+func TestParseRoutePattern_InvalidConstraint(t *testing.T) {
+	for _, path := range []string{"/users/:id<>", "/users/:id<int", "/users/:id<[a-z>"} {
+		if _, err := parseRoutePattern(path); err == nil {
+			t.Fatalf("%s: expected error", path)
+		}
+	}
+}
+
+func TestRouterDescribe_PathParamConstraints(t *testing.T) {
+	r := New()
+	RegisterHandler[struct{}, struct{}](r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/orgs/:org/users/:id<int>"))
+
+	desc := r.Describe()
+	if len(desc) != 1 {
+		t.Fatalf("expected 1 description, got %d", len(desc))
+	}
+	want := []PathParamDescription{{Name: "org"}, {Name: "id", Constraint: "int"}}
+	if !reflect.DeepEqual(desc[0].PathParams, want) {
+		t.Fatalf("expected path params %+v, got %+v", want, desc[0].PathParams)
+	}
+}
@@ -0,0 +1,227 @@
+package httprpc
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithName names the endpoint so its URL can be built with Router.URL or URLFor.
+func WithName[Req, Res any](name string) RegisterOption[Req, Res] {
+	return registerOptionFunc[Req, Res](func(o *registerOptions[Req, Res]) { o.name = name })
+}
+
+// WithNameWithMeta names the endpoint so its URL can be built with Router.URL or URLFor.
+func WithNameWithMeta[Req, Meta, Res any](name string) RegisterOptionWithMeta[Req, Meta, Res] {
+	return registerOptionWithMetaFunc[Req, Meta, Res](func(o *registerOptionsWithMeta[Req, Meta, Res]) { o.name = name })
+}
+
+// URL builds the path of the named endpoint, filling its params from params.
+// Every required param must be supplied and satisfy its constraint; optional
+// params and catch-alls may be omitted, unless a later param is supplied.
+// Values are path-escaped (catch-alls per segment). A value for a :param must
+// not contain "/", since the router splits the decoded path on it and could
+// not match the result; use a catch-all for such values. Host params are not
+// part of the result and are ignored.
+func (r *Router) URL(name string, params map[string]string) (string, error) {
+	names, err := r.namedRoutes()
+	if err != nil {
+		return "", err
+	}
+	route := names[name]
+	if route == nil {
+		return "", fmt.Errorf("unknown route name %q", name)
+	}
+	return route.url(params)
+}
+
+// URLFor builds the path of the named endpoint, taking param values from the
+// `path` tagged fields of meta. See Router.URL.
+func URLFor[Meta any](r *Router, name string, meta Meta) (string, error) {
+	params, err := pathParamsOf(meta)
+	if err != nil {
+		return "", fmt.Errorf("url for %q: %w", name, err)
+	}
+	return r.URL(name, params)
+}
+
+// namedRoute is the parsed path of a named endpoint.
+type namedRoute struct {
+	meta       *EndpointMeta
+	pattern    *routePattern
+	hostParams []string
+}
+
+// namedRoutes returns the named routes indexed when the route table was last
+// built, or indexes the current registrations if it was not built yet.
+func (r *Router) namedRoutes() (map[string]*namedRoute, error) {
+	if table := r.table.Load(); table != nil {
+		return table.names, nil
+	}
+	return indexNamedRoutes(r.endpointMetas())
+}
+
+// indexNamedRoutes parses the paths of the named endpoints in metas.
+// Names must be unique.
+func indexNamedRoutes(metas []*EndpointMeta) (map[string]*namedRoute, error) {
+	names := map[string]*namedRoute{}
+	for _, m := range metas {
+		if m == nil || m.Name == "" {
+			continue
+		}
+		if prev, ok := names[m.Name]; ok {
+			return nil, fmt.Errorf("duplicate route name %q: %s %s and %s %s", m.Name, prev.meta.Method, prev.meta.Path, m.Method, m.Path)
+		}
+		pattern, err := parseRoutePattern(m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", m.Name, err)
+		}
+		route := &namedRoute{meta: m, pattern: pattern}
+		if m.Host != "" {
+			hp, err := parseHostPattern(m.Host)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: %w", m.Name, err)
+			}
+			route.hostParams = hp.params
+		}
+		names[m.Name] = route
+	}
+	return names, nil
+}
+
+func (nr *namedRoute) url(params map[string]string) (string, error) {
+	pattern := nr.pattern
+	for name := range params {
+		if _, ok := pattern.param(name); !ok && !slices.Contains(nr.hostParams, name) {
+			return "", fmt.Errorf("unknown param %q for route %s", name, pattern.path)
+		}
+	}
+
+	var b strings.Builder
+	for i, seg := range pattern.segments {
+		if seg.kind == segmentStatic {
+			b.WriteString("/")
+			b.WriteString(seg.value)
+			continue
+		}
+		val, ok := params[seg.value]
+		if !ok || val == "" {
+			if !seg.optional && seg.kind != segmentCatchAll {
+				return "", fmt.Errorf("missing param %q for route %s", seg.value, pattern.path)
+			}
+			// Optional params only match as a prefix, so none may follow a missing one.
+			for _, next := range pattern.segments[i+1:] {
+				if next.kind != segmentStatic && params[next.value] != "" {
+					return "", fmt.Errorf("param %q needs optional param %q for route %s", next.value, seg.value, pattern.path)
+				}
+			}
+			break
+		}
+		if seg.kind == segmentCatchAll {
+			for part := range strings.SplitSeq(strings.Trim(val, "/"), "/") {
+				b.WriteString("/")
+				b.WriteString(url.PathEscape(part))
+			}
+			continue
+		}
+		if strings.Contains(val, "/") {
+			return "", fmt.Errorf("param %q value %q must not contain \"/\"", seg.value, val)
+		}
+		if !seg.constraint.accepts(val) {
+			return "", fmt.Errorf("param %q value %q does not satisfy constraint %s", seg.value, val, seg.constraint.key())
+		}
+		b.WriteString("/")
+		b.WriteString(url.PathEscape(val))
+	}
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	return b.String(), nil
+}
+
+// pathParamsOf collects the `path` tagged fields of meta. Nil pointers and
+// zero omitempty fields are left out.
+func pathParamsOf(meta any) (map[string]string, error) {
+	mv := reflect.ValueOf(meta)
+	for mv.Kind() == reflect.Pointer {
+		if mv.IsNil() {
+			return nil, nil
+		}
+		mv = mv.Elem()
+	}
+	mt := mv.Type()
+	if mt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("meta type %s must be a struct", mt)
+	}
+
+	out := map[string]string{}
+	for i := range mt.NumField() {
+		field := mt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, err := parseMetaTag(mt, field, "path", true)
+		if err != nil {
+			return nil, err
+		}
+		if !tag.found || tag.skip {
+			continue
+		}
+		fv := mv.Field(i)
+		if tag.omitempty && fv.IsZero() {
+			continue
+		}
+		val, ok, err := formatPathValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("path %s: %w", tag.name, err)
+		}
+		if ok {
+			out[tag.name] = val
+		}
+	}
+	return out, nil
+}
+
+// formatPathValue is the inverse of setFromStrings for scalar values.
+func formatPathValue(v reflect.Value) (string, bool, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		return formatPathValue(v.Elem())
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339), true, nil
+	}
+	marshaler, ok := v.Interface().(encoding.TextMarshaler)
+	if !ok && v.CanAddr() {
+		marshaler, ok = v.Addr().Interface().(encoding.TextMarshaler)
+	}
+	if ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return "", false, fmt.Errorf("marshal text: %w", err)
+		}
+		return string(text), true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), true, nil
+	default:
+		return "", false, fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+}
@@ -0,0 +1,152 @@
+package httprpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type orgUserMeta struct {
+	Org  string  `path:"org"`
+	ID   int     `path:"id"`
+	Tab  *string `path:"tab,omitempty"`
+	Auth string  `header:"authorization,omitempty"`
+}
+
+func newNamedRouter() *Router {
+	r := New()
+	api := r.Group("/api/v1")
+	RegisterHandlerM(api, GETM(func(context.Context, struct{}, orgUserMeta) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/orgs/:org/users/:id<int>/:tab?"), WithNameWithMeta[struct{}, orgUserMeta, struct{}]("user"))
+	RegisterHandler(api, GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/files/*path"), WithName[struct{}, struct{}]("file"))
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/"), WithName[struct{}, struct{}]("home"))
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/book/:chapter?/:section?"), WithName[struct{}, struct{}]("page"))
+	return r
+}
+
+func TestRouterURL(t *testing.T) {
+	r := newNamedRouter()
+
+	tests := []struct {
+		name    string
+		route   string
+		params  map[string]string
+		want    string
+		wantErr string
+	}{
+		{name: "required", route: "user", params: map[string]string{"org": "acme inc", "id": "7"}, want: "/api/v1/orgs/acme%20inc/users/7"},
+		{name: "optional", route: "user", params: map[string]string{"org": "acme", "id": "7", "tab": "posts"}, want: "/api/v1/orgs/acme/users/7/posts"},
+		{name: "catch-all", route: "file", params: map[string]string{"path": "docs/a b.pdf"}, want: "/api/v1/files/docs/a%20b.pdf"},
+		{name: "empty-catch-all", route: "file", want: "/api/v1/files"},
+		{name: "root", route: "home", want: "/"},
+		{name: "optional-prefix", route: "page", params: map[string]string{"chapter": "a"}, want: "/book/a"},
+		{name: "optional-gap", route: "page", params: map[string]string{"section": "b"}, wantErr: `param "section" needs optional param "chapter"`},
+		{name: "missing", route: "user", params: map[string]string{"org": "acme"}, wantErr: `missing param "id"`},
+		{name: "slash", route: "user", params: map[string]string{"org": "acme/labs", "id": "7"}, wantErr: `param "org" value "acme/labs" must not contain "/"`},
+		{name: "constraint", route: "user", params: map[string]string{"org": "acme", "id": "seven"}, wantErr: "does not satisfy constraint int"},
+		{name: "unknown-param", route: "home", params: map[string]string{"id": "1"}, wantErr: `unknown param "id"`},
+		{name: "unknown-route", route: "nope", wantErr: `unknown route name "nope"`},
+	}
+	// URL indexes registrations until the handler is built, then uses the
+	// index compiled with the route table.
+	for _, built := range []bool{false, true} {
+		if built {
+			if _, err := r.Handler(); err != nil {
+				t.Fatalf("handler build error: %v", err)
+			}
+			if r.table.Load().names["user"] == nil {
+				t.Fatalf("expected named routes to be indexed in the route table")
+			}
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/built=%v", tt.name, built), func(t *testing.T) {
+				got, err := r.URL(tt.route, tt.params)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != tt.want {
+					t.Fatalf("expected %q, got %q", tt.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	r := newNamedRouter()
+
+	got, err := URLFor(r, "user", orgUserMeta{Org: "acme", ID: 42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "/api/v1/orgs/acme/users/42" {
+		t.Fatalf("unexpected url %q", got)
+	}
+
+	tab := "settings"
+	got, err = URLFor(r, "user", orgUserMeta{Org: "acme", ID: 42, Tab: &tab})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "/api/v1/orgs/acme/users/42/settings" {
+		t.Fatalf("unexpected url %q", got)
+	}
+
+	if _, err := URLFor(r, "user", struct {
+		Org string `path:"org"`
+	}{Org: "acme"}); err == nil || !strings.Contains(err.Error(), `missing param "id"`) {
+		t.Fatalf("expected missing param error, got %v", err)
+	}
+}
+
+type tenantDocMeta struct {
+	Tenant string `path:"tenant"`
+	ID     int    `path:"id"`
+}
+
+func TestURLFor_HostParams(t *testing.T) {
+	r := New()
+	RegisterHandlerM(r.Host(":tenant.example.com"), GETM(func(context.Context, struct{}, tenantDocMeta) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/docs/:id"), WithNameWithMeta[struct{}, tenantDocMeta, struct{}]("doc"))
+	if _, err := r.Handler(); err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	got, err := URLFor(r, "doc", tenantDocMeta{Tenant: "acme", ID: 7})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "/docs/7" {
+		t.Fatalf("unexpected url %q", got)
+	}
+	if _, err := r.URL("doc", map[string]string{"id": "7", "org": "acme"}); err == nil || !strings.Contains(err.Error(), `unknown param "org"`) {
+		t.Fatalf("expected unknown param error, got %v", err)
+	}
+}
+
+func TestRouterURL_DuplicateName(t *testing.T) {
+	r := New()
+	for _, path := range []string{"/a", "/b"} {
+		RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (struct{}, error) {
+			return struct{}{}, nil
+		}, path), WithName[struct{}, struct{}]("dup"))
+	}
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), `duplicate route name "dup"`) {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+}
@@ -1,8 +1,10 @@
 package httprpc
 
 import (
+	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 )
 
 // Router is the main router for handling HTTP requests.
@@ -13,15 +15,82 @@ type Router struct {
 	tsGenCfg *TSClientGenConfig
 
 	fallback http.Handler
+
+	disableAutoHead    bool
+	disableAutoOptions bool
+
+	serveOnce sync.Once
+	served    http.Handler
+
+	// table is the route table built last by Handler or LiveHandler.Reload.
+	table atomic.Pointer[routeTable]
+}
+
+// RouterOption configures a Router.
+type RouterOption interface {
+	apply(*Router)
+}
+
+type routerOptionFunc func(*Router)
+
+func (f routerOptionFunc) apply(r *Router) { f(r) }
+
+// WithoutAutoHead disables answering HEAD requests with the GET handler of the same route.
+func WithoutAutoHead() RouterOption {
+	return routerOptionFunc(func(r *Router) { r.disableAutoHead = true })
+}
+
+// WithoutAutoOptions disables answering OPTIONS requests with the route's Allow header.
+func WithoutAutoOptions() RouterOption {
+	return routerOptionFunc(func(r *Router) { r.disableAutoOptions = true })
+}
+
+// WithStrictRegistration makes registration errors (invalid meta types, registering
+// after the handler is built, ...) panic immediately instead of being returned from
+// Handler, GenTS and GenTSDir. Useful in tests.
+func WithStrictRegistration() RouterOption {
+	return routerOptionFunc(func(r *Router) { r.strict = true })
+}
+
+// WithTrustedProxies sets the proxies whose X-Forwarded-For entries are trusted when
+// resolving `remote:"ip"` meta fields. Entries are CIDR prefixes or single addresses;
+// invalid entries are reported as registration errors.
+func WithTrustedProxies(proxies ...string) RouterOption {
+	return routerOptionFunc(func(r *Router) {
+		for _, p := range proxies {
+			prefix, err := parseTrustedProxy(p)
+			if err != nil {
+				r.registerError(fmt.Errorf("trusted proxy %q: %w", p, err))
+				continue
+			}
+			r.trustedProxies = append(r.trustedProxies, prefix)
+		}
+	})
+}
+
+// WithRedactedErrors hides the message of handler errors that are not meant
+// for clients, typically in production. Errors other than StatusError,
+// *Problem and ValidationError are logged and answered with a bare 500
+// Internal Server Error.
+func WithRedactedErrors() RouterOption {
+	return routerOptionFunc(func(r *Router) { r.redactErrors = true })
 }
 
 // New creates a new Router.
-func New() *Router {
+// By default, HEAD requests are served by the route's GET handler with the body
+// discarded, and OPTIONS requests are answered with the route's Allow header.
+func New(opts ...RouterOption) *Router {
 	eg := &EndpointGroup{}
 	eg.root = eg
-	return &Router{
+	r := &Router{
 		EndpointGroup: eg,
 	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(r)
+		}
+	}
+	return r
 }
 
 // MiddlewareOption configures middleware options.
@@ -35,7 +104,8 @@ func (r *Router) Use(middleware Middleware, middlewareOpts ...MiddlewareOption)
 }
 
 // Handler builds and returns an http.Handler for the registered endpoints.
-// It returns an error if routes are invalid (e.g., duplicate method+path).
+// It returns the errors recorded during registration, or an error if routes are
+// invalid (e.g., duplicate method+path).
 func (r *Router) Handler() (http.Handler, error) {
 	return r.buildHandler()
 }
@@ -1,11 +1,15 @@
 package httprpc
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultCodecDecode_EmptyBodyDoesNotError(t *testing.T) {
@@ -115,3 +119,215 @@ func TestDefaultCodecDecode_FieldNameFallback(t *testing.T) {
 		t.Fatalf("unexpected decoded req: %+v", got)
 	}
 }
+
+type queryPagination struct {
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+}
+
+type QueryPagination queryPagination
+
+type queryDateRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func TestDefaultCodecDecode_QueryNestedEmbeddedAndMaps(t *testing.T) {
+	type Req struct {
+		queryPagination
+		Sort    string            `json:"sort"`
+		Range   *queryDateRange   `json:"range"`
+		Filter  map[string]string `json:"filter"`
+		Labels  map[string][]int  `json:"labels"`
+		Missing map[string]string `json:"missing"`
+	}
+
+	codec := DefaultCodec[Req, struct{}]{}
+	query := url.Values{}
+	query.Set("page", "2")
+	query.Set("page_size", "50")
+	query.Set("sort", "name")
+	query.Set("range.from", "2024-01-01T00:00:00Z")
+	query.Set("range[to]", "2024-02-01T00:00:00Z")
+	query.Set("filter[status]", "active")
+	query.Set("filter.role", "admin")
+	query.Add("labels[ids][]", "1")
+	query.Add("labels[ids][]", "2")
+	req := httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), http.NoBody)
+
+	got, err := codec.DecodeQuery(req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Page != 2 || got.PageSize != 50 || got.Sort != "name" {
+		t.Fatalf("unexpected flattened fields: %+v", got)
+	}
+	if got.Range == nil || got.Range.From.Month() != time.January || got.Range.To.Month() != time.February {
+		t.Fatalf("unexpected nested struct: %+v", got.Range)
+	}
+	if !reflect.DeepEqual(got.Filter, map[string]string{"status": "active", "role": "admin"}) {
+		t.Fatalf("unexpected filter map: %v", got.Filter)
+	}
+	if !reflect.DeepEqual(got.Labels, map[string][]int{"ids": {1, 2}}) {
+		t.Fatalf("unexpected labels map: %v", got.Labels)
+	}
+	if got.Missing != nil {
+		t.Fatalf("expected untouched map to stay nil, got %v", got.Missing)
+	}
+}
+
+func TestDefaultCodecDecode_QueryOuterFieldShadowsEmbedded(t *testing.T) {
+	type Req struct {
+		*QueryPagination
+		Page string `json:"page"`
+	}
+
+	codec := DefaultCodec[Req, struct{}]{}
+	req := httptest.NewRequest(http.MethodGet, "/?page=first&page_size=10", http.NoBody)
+
+	got, err := codec.DecodeQuery(req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Page != "first" || got.QueryPagination == nil || got.PageSize != 10 {
+		t.Fatalf("unexpected decoded req: %+v", got)
+	}
+}
+
+func TestDefaultCodecDecode_QueryMapValueError(t *testing.T) {
+	type Req struct {
+		Limits map[string]int `json:"limits"`
+	}
+
+	codec := DefaultCodec[Req, struct{}]{}
+	req := httptest.NewRequest(http.MethodGet, "/?limits[cpu]=many", http.NoBody)
+
+	if _, err := codec.DecodeQuery(req); err == nil || !strings.Contains(err.Error(), "decode query limits.cpu") {
+		t.Fatalf("expected map value error, got %v", err)
+	}
+}
+
+func TestDecodePlans_CompiledAtRegistration(t *testing.T) {
+	type planQuery struct {
+		Page int `query:"page"`
+	}
+	type planMeta struct {
+		ID    int    `path:"id"`
+		Trace string `header:"x-trace-id,omitempty"`
+	}
+
+	r := New()
+	RegisterHandlerM(r.EndpointGroup, GETM(func(context.Context, planQuery, planMeta) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/items/:id"))
+
+	if _, ok := queryPlans.Load(reflect.TypeFor[planQuery]()); !ok {
+		t.Fatalf("expected query plan to be compiled at registration")
+	}
+	p, ok := metaPlans.Load(reflect.TypeFor[planMeta]())
+	if !ok {
+		t.Fatalf("expected meta plan to be compiled at registration")
+	}
+	plan, _ := p.(*metaPlan)
+	if len(plan.fields) != 2 || plan.fields[1].key != "X-Trace-Id" {
+		t.Fatalf("unexpected meta plan: %+v", plan.fields)
+	}
+}
+
+func TestDefaultCodecDecode_QueryDefaultsAndEnums(t *testing.T) {
+	type Req struct {
+		Page  int      `json:"page" default:"1"`
+		Sort  string   `json:"sort" default:"asc" enum:"asc,desc"`
+		Tags  []string `json:"tags" default:"new,sale"`
+		Limit int      `json:"limit" enum:"10,20,50"`
+	}
+
+	codec := DefaultCodec[Req, struct{}]{}
+	got, err := codec.DecodeQuery(httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Page != 1 || got.Sort != "asc" || !reflect.DeepEqual(got.Tags, []string{"new", "sale"}) || got.Limit != 0 {
+		t.Fatalf("expected defaults, got %+v", got)
+	}
+
+	got, err = codec.DecodeQuery(httptest.NewRequest(http.MethodGet, "/?page=3&sort=desc&limit=020", http.NoBody))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Page != 3 || got.Sort != "desc" || got.Limit != 20 {
+		t.Fatalf("unexpected decoded req: %+v", got)
+	}
+
+	_, err = codec.DecodeQuery(httptest.NewRequest(http.MethodGet, "/?sort=sideways", http.NoBody))
+	if err == nil || !strings.Contains(err.Error(), `decode query sort: "sideways" is not one of asc, desc`) {
+		t.Fatalf("expected enum error, got %v", err)
+	}
+}
+
+func TestDefaultCodecDecode_BodyDefaultsAndEnums(t *testing.T) {
+	type Options struct {
+		Mode string `json:"mode" default:"fast" enum:"fast,safe"`
+	}
+	type Req struct {
+		Name    string  `json:"name"`
+		Retries int     `json:"retries" default:"3"`
+		Options Options `json:"options"`
+	}
+
+	codec := DefaultCodec[Req, struct{}]{}
+	got, err := codec.DecodeBody(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"a"}`)))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Retries != 3 || got.Options.Mode != "fast" {
+		t.Fatalf("expected defaults, got %+v", got)
+	}
+
+	got, err = codec.DecodeBody(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"retries":0,"options":{"mode":"safe"}}`)))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Retries != 0 || got.Options.Mode != "safe" {
+		t.Fatalf("expected explicit values to win, got %+v", got)
+	}
+
+	_, err = codec.DecodeBody(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"options":{"mode":"reckless"}}`)))
+	if err == nil || !strings.Contains(err.Error(), `options.mode: "reckless" is not one of fast, safe`) {
+		t.Fatalf("expected enum error, got %v", err)
+	}
+}
+
+func TestDecodeRequestMeta_DefaultsAndEnums(t *testing.T) {
+	type Meta struct {
+		Region string `header:"x-region" default:"eu" enum:"eu,us"`
+		Format string `query:"format,omitempty" enum:"json,csv"`
+	}
+
+	got, err := decodeRequestMeta[Meta](httptest.NewRequest(http.MethodGet, "/", http.NoBody), nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got.Region != "eu" || got.Format != "" {
+		t.Fatalf("unexpected meta: %+v", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?format=xml", http.NoBody)
+	if _, err := decodeRequestMeta[Meta](req, nil); err == nil || !strings.Contains(err.Error(), `decode query format: "xml" is not one of json, csv`) {
+		t.Fatalf("expected enum error, got %v", err)
+	}
+}
+
+func TestRegisterHandler_InvalidDefaultTag(t *testing.T) {
+	type Req struct {
+		Sort string `json:"sort" default:"up" enum:"asc,desc"`
+	}
+
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, Req) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/items"))
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), `Req.Sort: default "up" is not one of asc, desc`) {
+		t.Fatalf("expected default tag error, got %v", err)
+	}
+}
@@ -2,22 +2,25 @@ package httprpc
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"sort"
 	"strings"
 )
 
 type routeMethods struct {
 	byMethod map[string]http.Handler
+	mount    http.Handler // serves every method for mounted subtrees
 	allow    string
-}
-
-type pathParamMatch struct {
-	values map[string]string
-}
+	// noAutoHead is set when the GET handler is a stream (see endpoint.NoAutoHead).
+	noAutoHead bool
 
-func (m pathParamMatch) ok() bool {
-	return m.values != nil
+	// sources and mountSource are the registrations behind the handlers, and
+	// mountTable the compiled table of a mounted Router, for Routes.
+	sources     map[string]*endpoint
+	mountSource *endpoint
+	mountTable  *routeTable
 }
 
 func collectMiddlewares(group *EndpointGroup) []*MiddlewareWithPriority {
@@ -37,13 +40,7 @@ func applyMiddlewares(h http.Handler, middlewares []*MiddlewareWithPriority) htt
 		return h
 	}
 
-	ordered := append([]*MiddlewareWithPriority(nil), middlewares...)
-	sort.SliceStable(ordered, func(i, j int) bool {
-		// Higher priority runs earlier (wraps outer), so we apply it later.
-		return ordered[i].Priority < ordered[j].Priority
-	})
-
-	for _, mw := range ordered {
+	for _, mw := range orderMiddlewares(middlewares) {
 		if mw == nil || mw.Middleware == nil {
 			continue
 		}
@@ -52,239 +49,434 @@ func applyMiddlewares(h http.Handler, middlewares []*MiddlewareWithPriority) htt
 	return h
 }
 
+// orderMiddlewares returns middlewares in the order they are applied: innermost first.
+func orderMiddlewares(middlewares []*MiddlewareWithPriority) []*MiddlewareWithPriority {
+	ordered := append([]*MiddlewareWithPriority(nil), middlewares...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		// Higher priority runs earlier (wraps outer), so we apply it later.
+		return ordered[i].Priority < ordered[j].Priority
+	})
+	return ordered
+}
+
+type segmentKind int
+
+const (
+	segmentStatic segmentKind = iota
+	segmentParam
+	segmentCatchAll
+)
+
+type routeSegment struct {
+	kind       segmentKind
+	value      string // literal text for static segments, param name otherwise
+	optional   bool
+	constraint *paramConstraint
+}
+
 type routePattern struct {
 	path     string
-	shape    string
-	segments []string
+	segments []routeSegment
 	params   []string
-	methods  *routeMethods
+}
+
+// param returns the segment declaring the named param.
+func (p *routePattern) param(name string) (routeSegment, bool) {
+	for _, seg := range p.segments {
+		if seg.kind != segmentStatic && seg.value == name {
+			return seg, true
+		}
+	}
+	return routeSegment{}, false
+}
+
+// requiredSegments returns the number of leading segments that must be present.
+func (p *routePattern) requiredSegments() int {
+	for i, seg := range p.segments {
+		if seg.optional {
+			return i
+		}
+	}
+	return len(p.segments)
 }
 
 func parseRoutePattern(path string) (*routePattern, error) {
 	path = normalizeRoutePath(path)
 	if path == "/" {
-		return &routePattern{path: path, shape: path, segments: []string{""}}, nil
+		return &routePattern{path: path}, nil
 	}
 	parts := strings.Split(strings.Trim(path, "/"), "/")
 	params := make([]string, 0, len(parts))
-	shapeParts := make([]string, 0, len(parts))
+	segments := make([]routeSegment, 0, len(parts))
 	seenParams := map[string]struct{}{}
-	for _, part := range parts {
-		if strings.Contains(part, "{") || strings.Contains(part, "}") {
-			return nil, fmt.Errorf("invalid path segment %q: use :name for params", part)
+	seenOptional := false
+	for i, part := range parts {
+		seg, err := parseRouteSegment(part)
+		if err != nil {
+			return nil, err
 		}
-		if strings.HasPrefix(part, ":") {
-			name := strings.TrimPrefix(part, ":")
-			if name == "" {
-				return nil, fmt.Errorf("invalid path segment %q: missing param name", part)
+		switch seg.kind {
+		case segmentStatic:
+			if seenOptional {
+				return nil, fmt.Errorf("invalid path segment %q: optional params must be trailing", part)
 			}
-			if strings.Contains(name, ":") {
-				return nil, fmt.Errorf("invalid path segment %q: unexpected ':'", part)
+			segments = append(segments, seg)
+			continue
+		case segmentCatchAll:
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("invalid path segment %q: catch-all must be the last segment", part)
 			}
-			if !isSnakeCase(name) {
-				return nil, fmt.Errorf("invalid path param %q: must be snake_case", name)
+			if seenOptional {
+				return nil, fmt.Errorf("invalid path segment %q: catch-all cannot follow optional params", part)
 			}
-			if _, ok := seenParams[name]; ok {
-				return nil, fmt.Errorf("duplicate path param %q in %s", name, path)
+		default:
+			if seenOptional && !seg.optional {
+				return nil, fmt.Errorf("invalid path segment %q: optional params must be trailing", part)
 			}
-			seenParams[name] = struct{}{}
-			params = append(params, name)
-			shapeParts = append(shapeParts, ":")
-			continue
+			seenOptional = seg.optional
 		}
-		if strings.Contains(part, ":") {
-			return nil, fmt.Errorf("invalid path segment %q: use :name for params", part)
+		if _, ok := seenParams[seg.value]; ok {
+			return nil, fmt.Errorf("duplicate path param %q in %s", seg.value, path)
 		}
-		shapeParts = append(shapeParts, part)
+		seenParams[seg.value] = struct{}{}
+		params = append(params, seg.value)
+		segments = append(segments, seg)
 	}
-	shape := "/" + strings.Join(shapeParts, "/")
 	return &routePattern{
 		path:     path,
-		shape:    shape,
-		segments: parts,
+		segments: segments,
 		params:   params,
 	}, nil
 }
 
-func (p *routePattern) match(path string) pathParamMatch {
-	if p.path == path {
-		return pathParamMatch{values: map[string]string{}}
-	}
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(p.segments) != len(parts) {
-		return pathParamMatch{}
-	}
-	values := make(map[string]string, len(p.params))
-	for i, seg := range p.segments {
-		if name, ok := pathParamName(seg); ok {
-			values[name] = parts[i]
-			continue
+func parseRouteSegment(part string) (routeSegment, error) {
+	var seg routeSegment
+	switch {
+	case strings.HasPrefix(part, ":"):
+		seg.kind = segmentParam
+		seg.value = strings.TrimPrefix(part, ":")
+		if strings.HasSuffix(seg.value, "?") {
+			seg.optional = true
+			seg.value = strings.TrimSuffix(seg.value, "?")
 		}
-		if seg != parts[i] {
-			return pathParamMatch{}
+		if open := strings.IndexByte(seg.value, '<'); open >= 0 {
+			if !strings.HasSuffix(seg.value, ">") {
+				return routeSegment{}, fmt.Errorf("invalid path segment %q: unterminated constraint", part)
+			}
+			constraint, err := parseParamConstraint(seg.value[open+1 : len(seg.value)-1])
+			if err != nil {
+				return routeSegment{}, fmt.Errorf("invalid path segment %q: %w", part, err)
+			}
+			seg.constraint = constraint
+			seg.value = seg.value[:open]
+		}
+	case strings.HasPrefix(part, "*"):
+		seg.kind = segmentCatchAll
+		seg.value = strings.TrimPrefix(part, "*")
+		if strings.HasSuffix(seg.value, "?") {
+			return routeSegment{}, fmt.Errorf("invalid path segment %q: catch-all is already optional", part)
+		}
+	default:
+		if strings.ContainsAny(part, "{}") {
+			return routeSegment{}, fmt.Errorf("invalid path segment %q: use :name for params", part)
 		}
+		if strings.ContainsAny(part, ":*?<>") {
+			return routeSegment{}, fmt.Errorf("invalid path segment %q: use :name for params", part)
+		}
+		return routeSegment{kind: segmentStatic, value: part}, nil
 	}
-	return pathParamMatch{values: values}
-}
 
-func pathParamName(segment string) (string, bool) {
-	const minPathParamSegmentLen = 2
-	if len(segment) < minPathParamSegmentLen {
-		return "", false
+	name := seg.value
+	if name == "" {
+		return routeSegment{}, fmt.Errorf("invalid path segment %q: missing param name", part)
 	}
-	if !strings.HasPrefix(segment, ":") {
-		return "", false
+	if strings.ContainsAny(name, ":*?<>{}") {
+		return routeSegment{}, fmt.Errorf("invalid path segment %q: unexpected %q", part, name[strings.IndexAny(name, ":*?<>{}")])
 	}
-	name := strings.TrimSpace(segment[1:])
-	if name == "" {
-		return "", false
+	if !isSnakeCase(name) {
+		return routeSegment{}, fmt.Errorf("invalid path param %q: must be snake_case", name)
 	}
-	return name, true
+	return seg, nil
 }
 
 // Handler returns an http.Handler that dispatches to registered endpoints.
-// Supports exact matches and "/:param" path segments.
+// Supports exact matches, "/:param" and optional "/:param?" segments, and a
+// trailing "/*param" catch-all. Static segments take precedence over params,
+// and params take precedence over catch-alls.
 func (r *Router) buildHandler() (http.Handler, error) {
-	root := r.EndpointGroup
-	if root != nil && root.root != nil {
-		root = root.root
+	table, err := r.compile(true)
+	if err != nil {
+		return nil, err
+	}
+	r.table.Store(table)
+	return table, nil
+}
+
+// compile builds a route table from the endpoints registered so far.
+// When seal is set, later registrations are rejected.
+func (r *Router) compile(seal bool) (*routeTable, error) {
+	root := r.rootGroup()
+	if err := root.registrationErr(); err != nil {
+		return nil, err
 	}
-	if root != nil {
+	if seal {
+		root.mu.Lock()
 		root.sealed = true
+		root.mu.Unlock()
 	}
+	// Hold the read lock throughout so group middleware chains cannot change
+	// while they are applied.
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	handlers, metas := slices.Clone(root.Handlers), slices.Clone(root.Metas)
 
-	var fallback http.Handler
+	table := &routeTable{root: root}
+	subMetas := map[*Router][]*EndpointMeta{}
 	if r.fallback != nil {
-		fallback = applyMiddlewares(r.fallback, collectMiddlewares(root))
+		table.fallback = applyMiddlewares(r.fallback, collectMiddlewares(root))
 	}
 
-	staticRoutes := make(map[string]*routeMethods, len(r.Handlers))
-	var patternRoutes []*routePattern
-	patternByShape := map[string]*routePattern{}
-	for _, e := range r.Handlers {
+	trees := map[string]*routeTree{}
+	var entries []*routeEntry
+	for _, e := range handlers {
 		if e == nil {
 			continue
 		}
 
-		pattern, err := parseRoutePattern(e.Path)
-		if err != nil {
+		var (
+			pattern *routePattern
+			err     error
+		)
+		if e.Mount {
+			if pattern, err = parseMountPattern(e.Path); err != nil {
+				return nil, fmt.Errorf("invalid mount %s: %w", e.Path, err)
+			}
+		} else if pattern, err = parseRoutePattern(e.Path); err != nil {
 			return nil, fmt.Errorf("invalid route %s %s: %w", e.Method, e.Path, err)
 		}
 
-		var m *routeMethods
-		if len(pattern.params) > 0 {
-			existing := patternByShape[pattern.shape]
-			if existing != nil && existing.path != pattern.path {
-				return nil, fmt.Errorf("ambiguous route: %s conflicts with %s", pattern.path, existing.path)
-			}
-			if existing == nil {
-				patternByShape[pattern.shape] = pattern
-				patternRoutes = append(patternRoutes, pattern)
-			} else {
-				pattern = existing
-			}
-			m = pattern.methods
-		} else {
-			m = staticRoutes[pattern.path]
+		var match *groupMatch
+		if e.Group != nil {
+			match = e.Group.match
 		}
-		if m == nil {
-			m = &routeMethods{byMethod: map[string]http.Handler{}}
-			if len(pattern.params) > 0 {
-				pattern.methods = m
-			} else {
-				staticRoutes[pattern.path] = m
+		hostParams := match.hostParams()
+		for _, name := range hostParams {
+			if _, ok := pattern.param(name); ok {
+				return nil, fmt.Errorf("invalid route %s %s: param %q is also a host param", e.Method, e.Path, name)
 			}
 		}
-		if _, exists := m.byMethod[e.Method]; exists {
-			return nil, fmt.Errorf("duplicate route: %s %s", e.Method, pattern.path)
+
+		tree := trees[match.key()]
+		if tree == nil {
+			tree = &routeTree{match: match, root: newRouteNode()}
+			trees[match.key()] = tree
+			table.trees = append(table.trees, tree)
+		}
+
+		variants, err := tree.root.insert(pattern, hostParams)
+		if err != nil {
+			return nil, err
 		}
 
 		h := e.Handler
 		if h == nil {
 			h = http.NotFoundHandler()
 		}
+		if e.Mount {
+			var subTable *routeTable
+			if sub, ok := h.(*Router); ok {
+				if subTable, err = sub.compile(seal); err != nil {
+					return nil, fmt.Errorf("mount %s: %w", e.Path, err)
+				}
+				subMetas[sub] = subTable.metas
+				h = subTable
+			}
+			h = applyMiddlewares(stripSegments(h, len(pattern.segments)-1), collectMiddlewares(e.Group))
+			entry := variants[0]
+			if entry.methods.mount != nil {
+				return nil, fmt.Errorf("duplicate mount: %s", normalizeRoutePath(e.Path))
+			}
+			entry.methods.mount = h
+			entry.methods.mountSource = e
+			entry.methods.mountTable = subTable
+			continue
+		}
 		h = applyMiddlewares(h, collectMiddlewares(e.Group))
-		m.byMethod[e.Method] = h
+		for _, entry := range variants {
+			m := entry.methods
+			if len(m.byMethod) == 0 {
+				entries = append(entries, entry)
+			}
+			if _, exists := m.byMethod[e.Method]; exists {
+				return nil, fmt.Errorf("duplicate route: %s %s", e.Method, pattern.path)
+			}
+			m.byMethod[e.Method] = h
+			if m.sources == nil {
+				m.sources = map[string]*endpoint{}
+			}
+			m.sources[e.Method] = e
+			if e.Method == http.MethodGet && e.NoAutoHead {
+				m.noAutoHead = true
+			}
+		}
 	}
 
-	for _, m := range staticRoutes {
-		if len(m.byMethod) == 0 {
-			continue
+	for _, tree := range table.trees {
+		if mount, conflict := tree.root.mountConflict(); conflict != nil {
+			return nil, fmt.Errorf("route %s conflicts with mount %s", conflict.pattern.path, strings.TrimSuffix(mount.pattern.path, "/*"))
 		}
-		methods := make([]string, 0, len(m.byMethod))
-		for method := range m.byMethod {
+	}
+
+	table.metas = appendMountedMetas(metas, handlers, func(sub *Router) []*EndpointMeta { return subMetas[sub] })
+	names, err := indexNamedRoutes(table.metas)
+	if err != nil {
+		return nil, err
+	}
+	table.names = names
+
+	// Trees with host/header matchers are tried before less specific ones.
+	sort.SliceStable(table.trees, func(i, j int) bool {
+		return table.trees[i].match.specificity() > table.trees[j].match.specificity()
+	})
+
+	for _, entry := range entries {
+		r.addAutoMethods(entry.methods, root)
+		methods := make([]string, 0, len(entry.methods.byMethod))
+		for method := range entry.methods.byMethod {
 			methods = append(methods, method)
 		}
 		sort.Strings(methods)
-		m.allow = strings.Join(methods, ", ")
+		entry.methods.allow = strings.Join(methods, ", ")
 	}
-	for _, p := range patternRoutes {
-		if p == nil || p.methods == nil || len(p.methods.byMethod) == 0 {
-			continue
+
+	return table, nil
+}
+
+// addAutoMethods registers the implicit HEAD and OPTIONS handlers for a route
+// unless they were registered explicitly or disabled on the router.
+// HEAD runs the GET handler with the writer unchanged: net/http drops the body
+// and still reports its Content-Length. Streaming GET handlers get no implicit
+// HEAD. The OPTIONS handler runs the middlewares of optionsGroup (e.g. CORS).
+func (r *Router) addAutoMethods(m *routeMethods, root *EndpointGroup) {
+	if get, ok := m.byMethod[http.MethodGet]; ok && !m.noAutoHead && !r.disableAutoHead {
+		if _, exists := m.byMethod[http.MethodHead]; !exists {
+			m.byMethod[http.MethodHead] = get
 		}
-		methods := make([]string, 0, len(p.methods.byMethod))
-		for method := range p.methods.byMethod {
-			methods = append(methods, method)
+	}
+	if _, exists := m.byMethod[http.MethodOptions]; !exists && !r.disableAutoOptions {
+		m.byMethod[http.MethodOptions] = applyMiddlewares(optionsHandler(m), collectMiddlewares(optionsGroup(m, root)))
+	}
+}
+
+// optionsGroup returns the group whose middlewares answer the route's
+// automatic OPTIONS: the group every method of the route was registered on,
+// so that a group-level CORS middleware sees preflight requests, or the root
+// group when several groups share the path.
+func optionsGroup(m *routeMethods, root *EndpointGroup) *EndpointGroup {
+	var group *EndpointGroup
+	for _, e := range m.sources {
+		if group != nil && e.Group != group {
+			return root
 		}
-		sort.Strings(methods)
-		p.methods.allow = strings.Join(methods, ", ")
+		group = e.Group
+	}
+	if group == nil {
+		return root
 	}
+	return group
+}
+
+// optionsHandler answers OPTIONS requests with the route's Allow header.
+// The header is read at request time since it is computed after registration.
+func optionsHandler(m *routeMethods) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Allow", m.allow)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		requestPath := normalizeRequestPath(req.URL.Path)
+// routeTable is a compiled set of routes, one tree per distinct set of
+// group matchers.
+type routeTable struct {
+	trees    []*routeTree
+	fallback http.Handler
+	// root is the compiled router's root group.
+	root *EndpointGroup
+	// metas are the metas of the compiled endpoints, including mounted routers',
+	// and names indexes the named ones for Router.URL.
+	metas []*EndpointMeta
+	names map[string]*namedRoute
+}
 
-		m := staticRoutes[requestPath]
-		if m != nil {
-			h := m.byMethod[req.Method]
-			if h == nil {
-				if m.allow != "" {
-					w.Header().Set("Allow", m.allow)
-				}
-				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-				return
-			}
-			h.ServeHTTP(w, req)
-			return
-		}
+type routeTree struct {
+	match *groupMatch
+	root  *routeNode
+}
 
-		for _, p := range patternRoutes {
-			if p == nil || p.methods == nil {
-				continue
-			}
-			match := p.match(requestPath)
-			if !match.ok() {
-				continue
-			}
-			h := p.methods.byMethod[req.Method]
-			if h == nil {
-				if p.methods.allow != "" {
-					w.Header().Set("Allow", p.methods.allow)
-				}
-				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-				return
-			}
-			req = withPathParams(req, match.values)
-			h.ServeHTTP(w, req)
-			return
+func (t *routeTable) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params := acquirePathParams()
+	path := strings.Trim(req.URL.Path, "/")
+	// allow collects the methods of matching routes without req.Method; a
+	// less specific tree may still serve it, so 405 is only the last resort.
+	var allow []string
+	for _, tree := range t.trees {
+		if !tree.match.matches(req, params) {
+			params.values = params.values[:0]
+			continue
+		}
+		entry := tree.root.lookup(path, params)
+		if entry == nil {
+			params.values = params.values[:0]
+			continue
+		}
+		h := entry.methods.byMethod[req.Method]
+		if h == nil {
+			h = entry.methods.mount
 		}
+		if h == nil {
+			allow = append(allow, entry.methods.allow)
+			params.values = params.values[:0]
+			continue
+		}
+		params.names = entry.params
+		h.ServeHTTP(w, withPathParams(req, params))
+		releasePathParams(params)
+		return
+	}
 
-		if fallback != nil {
-			fallback.ServeHTTP(w, req)
-			return
+	releasePathParams(params)
+	if allow != nil {
+		if header := mergeAllow(allow); header != "" {
+			w.Header().Set("Allow", header)
 		}
-		http.NotFound(w, req)
-	}), nil
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if t.fallback != nil {
+		t.fallback.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
 }
 
-func normalizeRoutePath(path string) string {
-	path = "/" + strings.Trim(path, "/")
-	if path == "/" {
-		return "/"
+// mergeAllow joins the Allow headers of several routes, sorted and deduplicated.
+func mergeAllow(allow []string) string {
+	if len(allow) == 1 {
+		return allow[0]
 	}
-	return path
+	var methods []string
+	for _, a := range allow {
+		for m := range strings.SplitSeq(a, ", ") {
+			if m != "" {
+				methods = append(methods, m)
+			}
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(slices.Compact(methods), ", ")
 }
 
-func normalizeRequestPath(path string) string {
+func normalizeRoutePath(path string) string {
 	path = "/" + strings.Trim(path, "/")
 	if path == "/" {
 		return "/"
@@ -294,19 +486,25 @@ func normalizeRequestPath(path string) string {
 
 // Describe returns endpoint metadata suitable for generators.
 func (r *Router) Describe() []EndpointDescription {
-	out := make([]EndpointDescription, 0, len(r.Metas))
-	for _, m := range r.Metas {
+	metas := r.liveMetas()
+	out := make([]EndpointDescription, 0, len(metas))
+	for _, m := range metas {
 		if m == nil {
 			continue
 		}
 		out = append(out, EndpointDescription{
-			Method:   m.Method,
-			Path:     m.Path,
-			Req:      typeRef(m.Req),
-			Meta:     typeRef(m.Meta),
-			Res:      typeRef(m.Res),
-			Consumes: append([]string(nil), m.Consumes...),
-			Produces: append([]string(nil), m.Produces...),
+			Name:       m.Name,
+			Method:     m.Method,
+			Path:       m.Path,
+			Host:       m.Host,
+			Headers:    maps.Clone(m.Headers),
+			PathParams: describePathParams(m.Path),
+			Req:        typeRef(m.Req),
+			Meta:       typeRef(m.Meta),
+			Res:        typeRef(m.Res),
+			Consumes:   append([]string(nil), m.Consumes...),
+			Produces:   append([]string(nil), m.Produces...),
+			Stream:     m.Stream,
 		})
 	}
 	return out
@@ -3,8 +3,11 @@ package httprpc
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -48,8 +51,161 @@ func TestRouterHandler_DispatchesAndMethodNotAllowed(t *testing.T) {
 	if rec.Code != http.StatusMethodNotAllowed {
 		t.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, rec.Code)
 	}
-	if allow := rec.Header().Get("Allow"); allow != http.MethodGet {
-		t.Fatalf("expected Allow %q, got %q", http.MethodGet, allow)
+	if allow := rec.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Fatalf("expected Allow %q, got %q", "GET, HEAD, OPTIONS", allow)
+	}
+}
+
+func TestRouterHandler_AutoHeadAndOptions(t *testing.T) {
+	r := New()
+	var middlewareCalls int
+	headFlusher := false
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			middlewareCalls++
+			if req.Method == http.MethodHead {
+				_, headFlusher = w.(http.Flusher)
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (string, error) {
+		return "pong", nil
+	}, "/ping"))
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, struct{}) (string, error) {
+		return "created", nil
+	}, "/items"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	get, err := http.Get(srv.URL + "/ping")
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	_ = get.Body.Close()
+	head, err := http.Head(srv.URL + "/ping")
+	if err != nil {
+		t.Fatalf("HEAD error: %v", err)
+	}
+	body, _ := io.ReadAll(head.Body)
+	_ = head.Body.Close()
+	if head.StatusCode != http.StatusOK || len(body) != 0 {
+		t.Fatalf("HEAD: expected an empty %d, got %d %q", http.StatusOK, head.StatusCode, body)
+	}
+	for _, key := range []string{"Content-Type", "Content-Length"} {
+		if got, want := head.Header.Get(key), get.Header.Get(key); got != want || want == "" {
+			t.Fatalf("HEAD: expected %s %q as for GET, got %q", key, want, got)
+		}
+	}
+	if !headFlusher {
+		t.Fatal("HEAD: expected the response writer to keep implementing http.Flusher")
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/ping", http.NoBody))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS: expected %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Fatalf("OPTIONS: unexpected Allow %q", allow)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/items", http.NoBody))
+	if allow := rec.Header().Get("Allow"); allow != "OPTIONS, POST" {
+		t.Fatalf("OPTIONS: unexpected Allow %q", allow)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/items", http.NoBody))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("HEAD without GET: expected %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+
+	if middlewareCalls != 4 {
+		t.Fatalf("expected root middleware to run for HEAD and OPTIONS, got %d calls", middlewareCalls)
+	}
+}
+
+func TestRouterHandler_AutoOptionsRunsGroupMiddlewares(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			next.ServeHTTP(w, req)
+		})
+	})
+	noop := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	RegisterHandler(api, GET(noop, "/items"))
+	RegisterHandler(api, POST(noop, "/items"))
+	RegisterHandler(api, GET(noop, "/shared"))
+	RegisterHandler(r.EndpointGroup, POST(noop, "/api/shared"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	for path, want := range map[string]string{"/api/items": "*", "/api/shared": ""} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, path, http.NoBody))
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != want {
+			t.Fatalf("%s: expected 204 with origin %q, got %d %v", path, want, rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestRouterHandler_AutoHeadAndOptionsDisabled(t *testing.T) {
+	r := New(WithoutAutoHead(), WithoutAutoOptions())
+	RegisterHandler(r.EndpointGroup, GET(func(context.Context, struct{}) (string, error) {
+		return "pong", nil
+	}, "/ping"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	for _, method := range []string{http.MethodHead, http.MethodOptions} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/ping", http.NoBody))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("%s: expected %d, got %d", method, http.StatusMethodNotAllowed, rec.Code)
+		}
+		if allow := rec.Header().Get("Allow"); allow != http.MethodGet {
+			t.Fatalf("%s: expected Allow %q, got %q", method, http.MethodGet, allow)
+		}
+	}
+}
+
+func TestRouterHandler_ExplicitHeadAndOptionsWin(t *testing.T) {
+	r := New()
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusOK, nil
+	}, "/ping"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](r.EndpointGroup, HEAD(func(context.Context, struct{}) (int, error) {
+		return http.StatusAccepted, nil
+	}, "/ping"), WithCodec[struct{}, int](statusCodec{}))
+	RegisterHandler[struct{}, int](r.EndpointGroup, OPTIONS(func(context.Context, struct{}) (int, error) {
+		return http.StatusTeapot, nil
+	}, "/ping"), WithCodec[struct{}, int](statusCodec{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	for method, want := range map[string]int{http.MethodHead: http.StatusAccepted, http.MethodOptions: http.StatusTeapot} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/ping", http.NoBody))
+		if rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d", method, want, rec.Code)
+		}
 	}
 }
 
@@ -216,6 +372,101 @@ func TestRouterHandler_MetaHeaders(t *testing.T) {
 	}
 }
 
+func TestRouterHandler_MetaCookieQueryAndRemote(t *testing.T) {
+	type meta struct {
+		Session string     `cookie:"session_id"`
+		DryRun  bool       `query:"dry_run,omitempty"`
+		IP      netip.Addr `remote:"ip"`
+		Addr    string     `remote:"addr"`
+	}
+
+	var got meta
+	r := New(WithTrustedProxies("10.0.0.0/8"))
+	RegisterHandlerM(r.EndpointGroup, POSTM(func(_ context.Context, _ struct{}, m meta) (struct{}, error) {
+		got = m
+		return struct{}{}, nil
+	}, "/jobs"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", http.NoBody))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "session_id") {
+		t.Fatalf("expected missing cookie error, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		wantIP     string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:1234", forwarded: []string{"198.51.100.1"}, wantIP: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:80", forwarded: []string{"198.51.100.1"}, wantIP: "198.51.100.1"},
+		{name: "trusted chain", remoteAddr: "10.0.0.2:80", forwarded: []string{"198.51.100.9, 198.51.100.1", "10.1.1.1"}, wantIP: "198.51.100.1"},
+		{name: "all trusted", remoteAddr: "10.0.0.2:80", forwarded: []string{"10.3.3.3"}, wantIP: "10.3.3.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = meta{}
+			req := httptest.NewRequest(http.MethodPost, "/jobs?dry_run=true", http.NoBody)
+			req.RemoteAddr = tt.remoteAddr
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: "abc"})
+			for _, f := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", f)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			if got.Session != "abc" || !got.DryRun || got.Addr != tt.remoteAddr || got.IP.String() != tt.wantIP {
+				t.Fatalf("unexpected meta: %+v", got)
+			}
+		})
+	}
+}
+
+func TestMetaPlan_RejectsInvalidSources(t *testing.T) {
+	type twoSources struct {
+		ID string `cookie:"id" query:"id"`
+	}
+	type badRemote struct {
+		Port string `remote:"port"`
+	}
+	type intRemote struct {
+		IP int `remote:"ip"`
+	}
+	type camelQuery struct {
+		DryRun bool `query:"dryRun"`
+	}
+
+	tests := []struct {
+		meta reflect.Type
+		want string
+	}{
+		{reflect.TypeFor[twoSources](), "cannot use both cookie and query tags"},
+		{reflect.TypeFor[badRemote](), `remote tag must be "ip" or "addr"`},
+		{reflect.TypeFor[intRemote](), "must bind a string"},
+		{reflect.TypeFor[camelQuery](), "must be snake_case"},
+	}
+	for _, tt := range tests {
+		if err := validateMetaType(tt.meta, "/", nil); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected %q error, got %v", tt.meta, tt.want, err)
+		}
+	}
+}
+
+func TestWithTrustedProxies_InvalidEntry(t *testing.T) {
+	r := New(WithTrustedProxies("10.0.0.0/8", "not-an-ip"))
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), `trusted proxy "not-an-ip"`) {
+		t.Fatalf("expected trusted proxy error, got %v", err)
+	}
+}
+
 func TestRouterHandler_PathParams_InvalidPattern(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -225,6 +476,9 @@ func TestRouterHandler_PathParams_InvalidPattern(t *testing.T) {
 		{name: "brace-syntax", path: "/users/{id}", errHint: "use :name"},
 		{name: "not-snake-case", path: "/users/:UserID", errHint: "snake_case"},
 		{name: "duplicate-name", path: "/users/:id/:id", errHint: "duplicate path param"},
+		{name: "catch-all-not-last", path: "/files/*path/meta", errHint: "catch-all must be the last segment"},
+		{name: "optional-not-trailing", path: "/docs/:section?/edit", errHint: "optional params must be trailing"},
+		{name: "catch-all-after-optional", path: "/docs/:section?/*rest", errHint: "catch-all cannot follow optional params"},
 	}
 
 	for _, tt := range tests {
@@ -293,7 +547,7 @@ func TestRouterHandler_PathNormalization(t *testing.T) {
 }
 
 func TestRouterHandler_PathParams_PatternOrder(t *testing.T) {
-	t.Run("first-registered-wins", func(t *testing.T) {
+	t.Run("static-segment-wins", func(t *testing.T) {
 		r := New()
 		RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
 			return http.StatusTeapot, nil
@@ -310,8 +564,152 @@ func TestRouterHandler_PathParams_PatternOrder(t *testing.T) {
 		rec := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/users/42", http.NoBody)
 		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/teams/42", http.NoBody)
+		h.ServeHTTP(rec, req)
 		if rec.Code != http.StatusTeapot {
 			t.Fatalf("expected %d, got %d", http.StatusTeapot, rec.Code)
 		}
 	})
+
+	t.Run("backtracks-to-param", func(t *testing.T) {
+		r := New()
+		RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+			return http.StatusOK, nil
+		}, "/users/new"), WithCodec[struct{}, int](statusCodec{}))
+		RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+			return http.StatusTeapot, nil
+		}, "/:kind/:id/edit"), WithCodec[struct{}, int](statusCodec{}))
+
+		h, err := r.Handler()
+		if err != nil {
+			t.Fatalf("handler build error: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/users/new/edit", http.NoBody)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusTeapot {
+			t.Fatalf("expected %d, got %d", http.StatusTeapot, rec.Code)
+		}
+	})
+}
+
+func TestRouterHandler_CatchAllParam(t *testing.T) {
+	type filesMeta struct {
+		Path string `path:"path"`
+	}
+	type filesRes struct {
+		Path string `json:"path"`
+	}
+
+	r := New()
+	RegisterHandlerM[struct{}, filesMeta, filesRes](r.EndpointGroup, GETM(func(_ context.Context, _ struct{}, meta filesMeta) (filesRes, error) {
+		return filesRes{Path: meta.Path}, nil
+	}, "/files/*path"))
+	RegisterHandler[struct{}, int](r.EndpointGroup, GET(func(context.Context, struct{}) (int, error) {
+		return http.StatusTeapot, nil
+	}, "/files/:id/meta"), WithCodec[struct{}, int](statusCodec{}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/files", want: ""},
+		{path: "/files/readme.md", want: "readme.md"},
+		{path: "/files/docs/2024/report.pdf", want: "docs/2024/report.pdf"},
+		{path: "/files/docs/meta/extra", want: "docs/meta/extra"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected %d, got %d", tt.path, http.StatusOK, rec.Code)
+		}
+		var got filesRes
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got.Path != tt.want {
+			t.Fatalf("%s: expected path %q, got %q", tt.path, tt.want, got.Path)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/files/docs/meta", http.NoBody)
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected param route to win over catch-all, got %d", rec.Code)
+	}
+}
+
+func TestRouterHandler_OptionalParam(t *testing.T) {
+	type docsMeta struct {
+		Section *string `path:"section,omitempty"`
+	}
+	type docsRes struct {
+		Section string `json:"section"`
+	}
+
+	r := New()
+	RegisterHandlerM[struct{}, docsMeta, docsRes](r.EndpointGroup, GETM(func(_ context.Context, _ struct{}, meta docsMeta) (docsRes, error) {
+		if meta.Section == nil {
+			return docsRes{Section: "index"}, nil
+		}
+		return docsRes{Section: *meta.Section}, nil
+	}, "/docs/:section?"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	for path, want := range map[string]string{"/docs": "index", "/docs/install": "install"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected %d, got %d", path, http.StatusOK, rec.Code)
+		}
+		var got docsRes
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got.Section != want {
+			t.Fatalf("%s: expected section %q, got %q", path, want, got.Section)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/docs/install/extra", http.NoBody)
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestValidateMetaType_CatchAllAndOptional(t *testing.T) {
+	type requiredOptional struct {
+		Section string `path:"section"`
+	}
+	type intCatchAll struct {
+		Path int `path:"path"`
+	}
+
+	if err := validateMetaType(reflect.TypeFor[requiredOptional](), "/docs/:section?", nil); err == nil || !strings.Contains(err.Error(), "omitempty") {
+		t.Fatalf("expected omitempty error, got %v", err)
+	}
+	if err := validateMetaType(reflect.TypeFor[intCatchAll](), "/files/*path", nil); err == nil || !strings.Contains(err.Error(), "must be a string") {
+		t.Fatalf("expected string error, got %v", err)
+	}
 }
@@ -0,0 +1,191 @@
+package httprpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEHandler streams events to one client. ctx is cancelled when the client
+// disconnects or a write fails. Returning ends the stream; a non-nil error is
+// sent as a final "error" event carrying problem details.
+type SSEHandler[Req, Event any] func(ctx context.Context, req Req, stream *SSEStream[Event]) error
+
+// SSEEndpoint is a GET endpoint that streams server-sent events.
+type SSEEndpoint[Req, Event any] struct {
+	Handler SSEHandler[Req, Event]
+	Path    string
+}
+
+// SSE creates an SSEEndpoint. The request is decoded from the query string.
+func SSE[Req, Event any](handler SSEHandler[Req, Event], path string) SSEEndpoint[Req, Event] {
+	return SSEEndpoint[Req, Event]{Handler: handler, Path: path}
+}
+
+// SSEEvent is an event with the optional fields of the event stream format.
+type SSEEvent[T any] struct {
+	// ID is remembered by the client and sent back as Last-Event-ID when it
+	// reconnects. It must not contain newlines.
+	ID   string
+	Data T
+	// Retry tells the client how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// SSEStream sends typed events to a client. Its methods are safe for
+// concurrent use; they fail once the handler has returned.
+type SSEStream[Event any] struct {
+	lastEventID string
+	rc          *http.ResponseController
+	w           http.ResponseWriter
+	cancel      context.CancelCauseFunc
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// errStreamClosed is returned by sends after the handler returned.
+var errStreamClosed = errors.New("stream closed")
+
+// LastEventID returns the Last-Event-ID the client reconnected with, or "" for
+// a new stream. Handlers use it to resume after the last delivered event.
+func (s *SSEStream[Event]) LastEventID() string { return s.lastEventID }
+
+// Send sends ev as an event without an id.
+func (s *SSEStream[Event]) Send(ev Event) error {
+	return s.SendEvent(SSEEvent[Event]{Data: ev})
+}
+
+// SendEvent sends ev with its id and retry fields and flushes it.
+func (s *SSEStream[Event]) SendEvent(ev SSEEvent[Event]) error {
+	if strings.ContainsAny(ev.ID, "\r\n\x00") {
+		return fmt.Errorf("sse: invalid event id %q", ev.ID)
+	}
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return fmt.Errorf("sse: encode event: %w", err)
+	}
+	var b strings.Builder
+	if ev.ID != "" {
+		b.WriteString("id: " + ev.ID + "\n")
+	}
+	if ev.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	return s.write(b.String())
+}
+
+func (s *SSEStream[Event]) write(chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errStreamClosed
+	}
+	if s.err != nil {
+		return s.err
+	}
+	_, err := s.w.Write([]byte(chunk))
+	if err == nil {
+		err = s.rc.Flush()
+	}
+	if err != nil {
+		s.err = fmt.Errorf("sse: write event: %w", err)
+		s.cancel(s.err)
+	}
+	return s.err
+}
+
+func (s *SSEStream[Event]) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+}
+
+// RegisterSSE registers a server-sent events endpoint with the endpoint group.
+// Heartbeat comments keep idle connections open; see WithHeartbeat.
+func RegisterSSE[Req, Event any](eg *EndpointGroup, in SSEEndpoint[Req, Event], opts ...StreamOption) {
+	o := newStreamOptions(opts)
+	path := eg.Prefix + in.Path
+	if err := compileRequestPlans(reflect.TypeFor[Req]()); err != nil {
+		eg.registerError(fmt.Errorf("register %s %s: invalid request %s: %w", http.MethodGet, path, reflect.TypeFor[Req](), err))
+		return
+	}
+
+	eg.addEndpoint(&endpoint{
+		Path:       path,
+		Method:     http.MethodGet,
+		Source:     funcSource(in.Handler),
+		Handler:    adaptSSEHandler(in.Handler, o.heartbeat, eg.handlerConfig()),
+		Group:      eg,
+		NoAutoHead: true,
+	}, &EndpointMeta{
+		Name:     o.name,
+		Method:   http.MethodGet,
+		Path:     path,
+		Host:     eg.match.hostPattern(),
+		Headers:  eg.match.headerMap(),
+		Req:      reflect.TypeFor[Req](),
+		Res:      reflect.TypeFor[Event](),
+		Produces: []string{"text/event-stream"},
+		Stream:   StreamSSE,
+	})
+}
+
+func adaptSSEHandler[Req, Event any](handler SSEHandler[Req, Event], heartbeat time.Duration, cfg handlerConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeStreamRequest[Req](w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		stream := &SSEStream[Event]{
+			lastEventID: r.Header.Get("Last-Event-ID"),
+			rc:          http.NewResponseController(w),
+			w:           w,
+			cancel:      cancel,
+		}
+		// Streams outlive the server's WriteTimeout.
+		_ = stream.rc.SetWriteDeadline(time.Time{})
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		// Flushing commits the headers with an implicit 200, so a writer that
+		// cannot stream still gets to answer with an error instead.
+		if err := stream.rc.Flush(); err != nil {
+			if errors.Is(err, http.ErrNotSupported) {
+				slog.Error("response writer does not support streaming", "error", err, "method", r.Method, "path", r.URL.Path)
+				h.Del("Cache-Control")
+				h.Del("X-Accel-Buffering")
+				writeStreamError(w, StatusError{Status: http.StatusInternalServerError})
+			}
+			return
+		}
+
+		stopHeartbeat := startTicker(ctx, heartbeat, func() { _ = stream.write(":\n\n") })
+		err := handler(ctx, req, stream)
+		stopHeartbeat()
+		if err != nil && ctx.Err() == nil {
+			err = cfg.handlerError(r, err)
+			if data, marshalErr := json.Marshal(problemFor(err)); marshalErr == nil {
+				_ = stream.write("event: error\ndata: " + string(data) + "\n\n")
+			}
+		}
+		stream.close()
+	})
+}
@@ -0,0 +1,244 @@
+package httprpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type progressRequest struct {
+	Job   string `json:"job" query:"job" validate:"required"`
+	Steps int    `json:"steps" query:"steps"`
+}
+
+type progressEvent struct {
+	Step int `json:"step"`
+}
+
+func progressHandler(_ context.Context, req progressRequest, stream *SSEStream[progressEvent]) error {
+	start := 0
+	if id := stream.LastEventID(); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return StatusError{Status: http.StatusBadRequest, Err: err}
+		}
+		start = n
+	}
+	for step := start + 1; step <= req.Steps; step++ {
+		if err := stream.SendEvent(SSEEvent[progressEvent]{ID: strconv.Itoa(step), Data: progressEvent{Step: step}}); err != nil {
+			return err
+		}
+	}
+	if req.Job == "fail" {
+		return errors.New("job crashed")
+	}
+	return nil
+}
+
+func newSSEServer(t *testing.T, handler SSEHandler[progressRequest, progressEvent], opts ...StreamOption) *httptest.Server {
+	t.Helper()
+	r := New(WithRedactedErrors())
+	RegisterSSE(r.EndpointGroup, SSE(handler, "/progress"), opts...)
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getSSE(t *testing.T, url, lastEventID string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+	body, _ := io.ReadAll(res.Body)
+	return res, string(body)
+}
+
+func TestSSE_StreamsTypedEvents(t *testing.T) {
+	srv := newSSEServer(t, progressHandler)
+
+	res, body := getSSE(t, srv.URL+"/progress?job=build&steps=2", "")
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %v", res.StatusCode, res.Header)
+	}
+	want := "id: 1\ndata: {\"step\":1}\n\nid: 2\ndata: {\"step\":2}\n\n"
+	if body != want {
+		t.Fatalf("unexpected body:\n%q\nwant\n%q", body, want)
+	}
+
+	_, body = getSSE(t, srv.URL+"/progress?job=build&steps=3", "2")
+	if body != "id: 3\ndata: {\"step\":3}\n\n" {
+		t.Fatalf("expected resumption after event 2, got %q", body)
+	}
+}
+
+func TestSSE_Errors(t *testing.T) {
+	srv := newSSEServer(t, progressHandler)
+
+	res, body := getSSE(t, srv.URL+"/progress?steps=1", "")
+	if res.StatusCode != http.StatusUnprocessableEntity || res.Header.Get("Content-Type") != ProblemContentType {
+		t.Fatalf("expected a problem response for an invalid request, got %d %s", res.StatusCode, body)
+	}
+
+	_, body = getSSE(t, srv.URL+"/progress?job=fail&steps=1", "")
+	want := "id: 1\ndata: {\"step\":1}\n\nevent: error\ndata: {\"title\":\"Internal Server Error\",\"status\":500}\n\n"
+	if body != want {
+		t.Fatalf("unexpected body:\n%q\nwant\n%q", body, want)
+	}
+}
+
+func TestSSE_NoAutoHead(t *testing.T) {
+	called := false
+	srv := newSSEServer(t, func(context.Context, progressRequest, *SSEStream[progressEvent]) error {
+		called = true
+		return nil
+	})
+
+	req, _ := http.NewRequest(http.MethodHead, srv.URL+"/progress?job=build", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed || res.Header.Get("Allow") != "GET, OPTIONS" {
+		t.Fatalf("expected 405 for HEAD on a stream, got %d (Allow %q)", res.StatusCode, res.Header.Get("Allow"))
+	}
+	if called {
+		t.Fatal("expected HEAD not to run the stream handler")
+	}
+}
+
+// plainWriter hides the optional interfaces of the recorder it wraps.
+type plainWriter struct{ rec *httptest.ResponseRecorder }
+
+func (w plainWriter) Header() http.Header         { return w.rec.Header() }
+func (w plainWriter) Write(b []byte) (int, error) { return w.rec.Write(b) }
+func (w plainWriter) WriteHeader(status int)      { w.rec.WriteHeader(status) }
+
+func TestSSE_RequiresFlusher(t *testing.T) {
+	r := New()
+	called := false
+	RegisterSSE(r.EndpointGroup, SSE(func(context.Context, progressRequest, *SSEStream[progressEvent]) error {
+		called = true
+		return nil
+	}, "/progress"))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(plainWriter{rec}, httptest.NewRequest(http.MethodGet, "/progress?job=a", http.NoBody))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Fatalf("expected problem content type, got %q", got)
+	}
+	if called {
+		t.Fatalf("expected handler not to run without a flushing writer")
+	}
+}
+
+func TestSSE_HeartbeatAndDisconnect(t *testing.T) {
+	done := make(chan error, 1)
+	srv := newSSEServer(t, func(ctx context.Context, _ progressRequest, stream *SSEStream[progressEvent]) error {
+		if err := stream.Send(progressEvent{Step: 1}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		done <- ctx.Err()
+		return ctx.Err()
+	}, WithHeartbeat(10*time.Millisecond))
+
+	res, err := http.Get(srv.URL + "/progress?job=watch")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	lines := bufio.NewReader(res.Body)
+	var got []string
+	for len(got) < 4 {
+		line, err := lines.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		got = append(got, line)
+	}
+	if strings.Join(got, "") != "data: {\"step\":1}\n\n:\n\n" {
+		t.Fatalf("expected an event then a heartbeat, got %q", got)
+	}
+	_ = res.Body.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not cancelled after the client disconnected")
+	}
+}
+
+func TestRouterGenTS_SSE(t *testing.T) {
+	r := New()
+	RegisterSSE(r.EndpointGroup, SSE(progressHandler, "/jobs/:id/progress"), WithStreamName("progress"))
+
+	desc := r.Describe()
+	if len(desc) != 1 || desc[0].Stream != StreamSSE || desc[0].Res.Name != "progressEvent" || desc[0].Method != http.MethodGet {
+		t.Fatalf("unexpected description %+v", desc)
+	}
+	if u, err := r.URL("progress", map[string]string{"id": "7"}); err != nil || u != "/jobs/7/progress" {
+		t.Fatalf("unexpected URL %q, %v", u, err)
+	}
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"params: {id: string | number },\n    req?: progressRequest,\n    options?: StreamOptions,\n  ): AsyncGenerator<progressEvent, void, undefined> {",
+		`return this.sse<progressEvent>("/jobs/:id/progress", req, params, options)`,
+		"private async *sse<TEvent>(",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, out)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := r.GenTSDir(dir, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTSDir error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Clean(filepath.Join(dir, "jobs.ts")))
+	if err != nil {
+		t.Fatalf("read module: %v", err)
+	}
+	module := string(data)
+	for _, want := range []string{
+		"import type { ClientOptions, StreamOptions } from './base'\nimport { sse } from './base'\n",
+		`return sse<progressEvent>(this.opts, "/jobs/:id/progress", req, params, options)`,
+	} {
+		if !strings.Contains(module, want) {
+			t.Fatalf("expected %q in module:\n%s", want, module)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package httprpc
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StreamKind identifies the kind of a streaming endpoint in EndpointMeta and
+// EndpointDescription. It is empty for request/response endpoints.
+type StreamKind string
+
+const (
+	// StreamSSE endpoints send server-sent events (text/event-stream).
+	StreamSSE StreamKind = "sse"
+	// StreamNDJSON endpoints send newline-delimited JSON (application/x-ndjson).
+	StreamNDJSON StreamKind = "ndjson"
+	// StreamWebSocket endpoints upgrade to a WebSocket; Req is the type of
+	// incoming messages and Res the type of outgoing ones.
+	StreamWebSocket StreamKind = "websocket"
+)
+
+// DefaultHeartbeat is how often idle streams are kept alive unless
+// WithHeartbeat says otherwise.
+const DefaultHeartbeat = 15 * time.Second
+
+// StreamOption configures a streaming endpoint.
+type StreamOption interface {
+	apply(*streamOptions)
+}
+
+type streamOptions struct {
+	name           string
+	heartbeat      time.Duration
+	flushInterval  time.Duration
+	readLimit      int64
+	allowedOrigins []string
+}
+
+type streamOptionFunc func(*streamOptions)
+
+func (f streamOptionFunc) apply(o *streamOptions) { f(o) } //nolint:unused // interface method
+
+// WithStreamName names the streaming endpoint so its URL can be built with
+// Router.URL or URLFor.
+func WithStreamName(name string) StreamOption {
+	return streamOptionFunc(func(o *streamOptions) { o.name = name })
+}
+
+// WithHeartbeat sets how often an idle server-sent event stream is kept
+// alive, and how often a WebSocket is pinged. A WebSocket that leaves a ping
+// unanswered for an interval (at least one second) is closed. Zero or a
+// negative duration disables heartbeats.
+func WithHeartbeat(d time.Duration) StreamOption {
+	return streamOptionFunc(func(o *streamOptions) { o.heartbeat = d })
+}
+
+// WithFlushInterval sets how long NDJSON items may be buffered before they
+// are flushed to the client. Zero flushes after every item.
+func WithFlushInterval(d time.Duration) StreamOption {
+	return streamOptionFunc(func(o *streamOptions) { o.flushInterval = d })
+}
+
+// WithReadLimit sets the largest incoming WebSocket message in bytes. Larger
+// messages close the connection with WSCloseMessageTooBig.
+func WithReadLimit(n int64) StreamOption {
+	return streamOptionFunc(func(o *streamOptions) { o.readLimit = n })
+}
+
+// WithAllowedOrigins sets the origins browsers may open a WebSocket from, as
+// path.Match patterns on the Origin host (e.g. "app.example.com" or
+// "*.example.com"). By default only the request's own host is allowed.
+func WithAllowedOrigins(patterns ...string) StreamOption {
+	return streamOptionFunc(func(o *streamOptions) { o.allowedOrigins = append(o.allowedOrigins, patterns...) })
+}
+
+func newStreamOptions(opts []StreamOption) streamOptions {
+	o := streamOptions{heartbeat: DefaultHeartbeat, flushInterval: DefaultFlushInterval, readLimit: DefaultWSReadLimit}
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(&o)
+		}
+	}
+	return o
+}
+
+// decodeStreamRequest decodes and validates the request of a streaming
+// endpoint as DefaultCodec does. On failure it writes the error response and
+// reports false.
+func decodeStreamRequest[Req any](w http.ResponseWriter, r *http.Request) (Req, bool) {
+	codec := DefaultCodec[Req, struct{}]{}
+	var req Req
+	var err error
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		req, err = codec.DecodeQuery(r)
+	} else {
+		req, err = codec.DecodeBody(r)
+	}
+	if err != nil {
+		err = decodeFailure(err)
+	} else if violations := validateValue(&req, false); len(violations) > 0 {
+		err = validationFailure(violations)
+	}
+	if err == nil {
+		return req, true
+	}
+	writeStreamError(w, err)
+	return req, false
+}
+
+// writeStreamError writes err as the response of a stream that has not started.
+func writeStreamError(w http.ResponseWriter, err error) {
+	if encodeErr := (DefaultCodec[struct{}, struct{}]{}).EncodeError(w, err); encodeErr != nil {
+		slog.Error("failed to encode error response", "error", encodeErr)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// startTicker calls tick every interval until ctx is done or the returned
+// stop function is called. stop waits for a running tick to finish and may be
+// called more than once. A zero or negative interval never ticks.
+func startTicker(ctx context.Context, interval time.Duration, tick func()) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				tick()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
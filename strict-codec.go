@@ -0,0 +1,270 @@
+package httprpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultMaxBodyBytes is the body limit StrictJSONCodec uses when MaxBytes is zero.
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultMaxJSONDepth is the nesting limit StrictJSONCodec uses when MaxDepth is zero.
+	DefaultMaxJSONDepth = 32
+)
+
+// StrictJSONCodec is DefaultCodec with strict body decoding: unknown object
+// fields, trailing data after the JSON value, bodies larger than MaxBytes and
+// values nested deeper than MaxDepth are rejected. Errors name the offending
+// JSON pointer. Use it per endpoint with WithCodec.
+type StrictJSONCodec[Req any, Res any] struct {
+	DefaultCodec[Req, Res]
+
+	// MaxBytes limits the request body size. Zero means DefaultMaxBodyBytes.
+	MaxBytes int64
+	// MaxDepth limits how deeply objects and arrays may nest. Zero means DefaultMaxJSONDepth.
+	MaxDepth int
+}
+
+// DecodeBody decodes the request body into the request type using strict JSON rules.
+func (c StrictJSONCodec[Req, Res]) DecodeBody(r *http.Request) (Req, error) {
+	var req Req
+	plan := bodyPlanFor(reflect.TypeFor[Req]())
+	if plan.err != nil {
+		return req, fmt.Errorf("decode request: %w", plan.err)
+	}
+	rv := reflect.ValueOf(&req).Elem()
+	plan.applyDefaults(rv)
+	if r.Body == nil {
+		return req, nil
+	}
+	data, err := readLimitedBody(r, c.MaxBytes)
+	if err != nil {
+		return req, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return req, nil
+	}
+
+	maxDepth := c.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxJSONDepth
+	}
+	w := strictJSONWalker{dec: json.NewDecoder(bytes.NewReader(data)), maxDepth: maxDepth}
+	if err := w.value(reflect.TypeFor[Req](), "", 0); err != nil {
+		return req, fmt.Errorf("decode request: %w", err)
+	}
+	if _, err := w.dec.Token(); !errors.Is(err, io.EOF) {
+		return req, fmt.Errorf("decode request: unexpected data after JSON value at offset %d", w.dec.InputOffset())
+	}
+
+	if err := json.Unmarshal(data, &req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return req, fmt.Errorf("decode request: %s: cannot use JSON %s as %s", jsonPointerFromField(typeErr.Field), typeErr.Value, typeErr.Type)
+		}
+		return req, fmt.Errorf("decode request: %w", err)
+	}
+	if err := plan.checkEnums(rv); err != nil {
+		return req, fmt.Errorf("decode request: %w", err)
+	}
+	return req, nil
+}
+
+// readLimitedBody reads and closes r's body. Bodies larger than maxBytes
+// (DefaultMaxBodyBytes if zero) are rejected with 413.
+func readLimitedBody(r *http.Request, maxBytes int64) ([]byte, error) {
+	defer func() { _ = r.Body.Close() }()
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read request: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, StatusError{
+			Status: http.StatusRequestEntityTooLarge,
+			Err:    fmt.Errorf("decode request: body exceeds %d bytes", maxBytes),
+		}
+	}
+	return data, nil
+}
+
+// strictJSONWalker checks a JSON document against a Go type before it is
+// unmarshalled, tracking the JSON pointer of the current value.
+type strictJSONWalker struct {
+	dec      *json.Decoder
+	maxDepth int
+}
+
+// value consumes one JSON value. t is the Go type it decodes into, or nil if
+// any shape is accepted (interfaces and custom unmarshalers).
+func (w *strictJSONWalker) value(t reflect.Type, ptr string, depth int) error {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return fmt.Errorf("%s: %w", pointerOrRoot(ptr), err)
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok || delim == '}' || delim == ']' {
+		return nil
+	}
+	if depth+1 > w.maxDepth {
+		return fmt.Errorf("%s: exceeds max depth %d", pointerOrRoot(ptr), w.maxDepth)
+	}
+	t = strictShape(t)
+
+	if delim == '[' {
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := 0; w.dec.More(); i++ {
+			if err := w.value(elem, ptr+"/"+strconv.Itoa(i), depth+1); err != nil {
+				return err
+			}
+		}
+	} else {
+		for w.dec.More() {
+			keyTok, err := w.dec.Token()
+			if err != nil {
+				return fmt.Errorf("%s: %w", pointerOrRoot(ptr), err)
+			}
+			key, _ := keyTok.(string)
+			child := ptr + "/" + escapeJSONPointer(key)
+			var elem reflect.Type
+			if t != nil {
+				switch t.Kind() {
+				case reflect.Struct:
+					f, ok := jsonFieldsOf(t).lookup(key)
+					if !ok {
+						return fmt.Errorf("%s: unknown field", child)
+					}
+					elem = f
+				case reflect.Map:
+					elem = t.Elem()
+				default:
+				}
+			}
+			if err := w.value(elem, child, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := w.dec.Token(); err != nil { // closing delimiter
+		return fmt.Errorf("%s: %w", pointerOrRoot(ptr), err)
+	}
+	return nil
+}
+
+// strictShape dereferences t and returns nil for types whose JSON shape is not
+// derived from their Go structure.
+func strictShape(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	t = deref(t)
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	pt := reflect.PointerTo(t)
+	if pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return nil
+	}
+	return t
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// jsonFields maps the JSON names of a struct's fields (including promoted
+// embedded fields) to their types.
+type jsonFields map[string]reflect.Type
+
+// lookup matches key like encoding/json: exactly, then case-insensitively.
+func (f jsonFields) lookup(key string) (reflect.Type, bool) {
+	if t, ok := f[key]; ok {
+		return t, true
+	}
+	for name, t := range f {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+var strictFields sync.Map // reflect.Type -> jsonFields
+
+func jsonFieldsOf(t reflect.Type) jsonFields {
+	if f, ok := strictFields.Load(t); ok {
+		fields, _ := f.(jsonFields)
+		return fields
+	}
+	fields := jsonFields{}
+	collectJSONFields(t, fields, 0, map[string]int{})
+	f, _ := strictFields.LoadOrStore(t, fields)
+	out, _ := f.(jsonFields)
+	return out
+}
+
+// collectJSONFields adds t's fields to out. Shallower fields win over
+// promoted ones with the same name, as in encoding/json.
+func collectJSONFields(t reflect.Type, out jsonFields, depth int, depths map[string]int) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if isPromotedJSONEmbed(f) {
+			collectJSONFields(deref(f.Type), out, depth+1, depths)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, found, skip := tagName(f, "json")
+		if skip {
+			continue
+		}
+		if !found || name == "" {
+			name = f.Name
+		}
+		if d, ok := depths[name]; ok && d <= depth {
+			continue
+		}
+		depths[name] = depth
+		out[name] = f.Type
+	}
+}
+
+// escapeJSONPointer escapes a reference token per RFC 6901.
+func escapeJSONPointer(s string) string {
+	if !strings.ContainsAny(s, "~/") {
+		return s
+	}
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// jsonPointerFromField converts encoding/json's dotted field path to a JSON pointer.
+func jsonPointerFromField(field string) string {
+	if field == "" {
+		return pointerOrRoot("")
+	}
+	parts := strings.Split(field, ".")
+	for i, p := range parts {
+		parts[i] = escapeJSONPointer(p)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// pointerOrRoot names the document root, whose JSON pointer is empty.
+func pointerOrRoot(ptr string) string {
+	if ptr == "" {
+		return "(root)"
+	}
+	return ptr
+}
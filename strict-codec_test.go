@@ -0,0 +1,80 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type strictItem struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+type strictOrder struct {
+	ID    string            `json:"id"`
+	Items []strictItem      `json:"items"`
+	Attrs map[string]string `json:"attrs"`
+	Extra any               `json:"extra"`
+}
+
+func TestStrictJSONCodec_DecodeBody(t *testing.T) {
+	codec := StrictJSONCodec[strictOrder, struct{}]{MaxBytes: 256, MaxDepth: 3}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "valid", body: `{"id":"a","items":[{"sku":"x","qty":1}],"attrs":{"any":"key"},"extra":{"free":[1]}}`},
+		{name: "case-insensitive field", body: `{"ID":"a"}`},
+		{name: "empty body", body: ""},
+		{name: "unknown field", body: `{"id":"a","nope":1}`, want: "decode request: /nope: unknown field"},
+		{name: "unknown nested field", body: `{"items":[{"sku":"x"},{"sku":"y","price":2}]}`, want: "/items/1/price: unknown field"},
+		{name: "escaped pointer", body: `{"a/b":1}`, want: "/a~1b: unknown field"},
+		{name: "trailing data", body: `{"id":"a"} {"id":"b"}`, want: "unexpected data after JSON value"},
+		{name: "too deep", body: `{"extra":{"a":{"b":{"c":1}}}}`, want: "/extra/a/b: exceeds max depth 3"},
+		{name: "type error", body: `{"items":[{"qty":"many"}]}`, want: "/items/0/qty: cannot use JSON string as int"},
+		{name: "syntax error", body: `{"id":`, want: "decode request:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := codec.DecodeBody(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("expected nil error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStrictJSONCodec_BodyTooLarge(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, strictOrder) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/orders"), WithCodec[strictOrder, struct{}](StrictJSONCodec[strictOrder, struct{}]{MaxBytes: 16}))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":"0123456789abcdef"}`)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected %d, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":"a","x":1}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
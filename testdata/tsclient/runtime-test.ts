@@ -1,3 +1,4 @@
+import { ApiError, isApiError } from './base'
 import { UsersClient } from './users'
 
 async function main() {
@@ -7,17 +8,147 @@ async function main() {
     return { ok: true, status: 200, json: async () => ({ id: 1 }) }
   })
   const client = new UsersClient({ baseUrl: 'http://example.com', fetch: fetchImpl })
-  await client.get_users_id({ id: 123 }, { authorization: 'token' }, { q: 'hi' })
+  await client.get_users_id({ id: 123 }, { authorization: 'token' }, { q: 'hi', filter: { status: 'active' } })
   if (calls.length !== 1) {
     throw new Error('expected one request')
   }
   const call = calls[0]
-  if (call.url !== 'http://example.com/users/123?q=hi') {
+  if (call.url !== 'http://example.com/users/123?q=hi&filter%5Bstatus%5D=active') {
     throw new Error('unexpected url: ' + call.url)
   }
   if (!call.init || !call.init.headers || call.init.headers.authorization !== 'token') {
     throw new Error('missing auth header')
   }
+
+  const failing = new UsersClient({
+    baseUrl: 'http://example.com',
+    fetch: (async () => ({
+      ok: false,
+      status: 409,
+      statusText: 'Conflict',
+      headers: { get: () => 'application/problem+json' },
+      text: async () => JSON.stringify({ title: 'Conflict', status: 409, detail: 'taken', code: 'user_exists', details: { id: 7 } }),
+    })) as unknown as typeof fetch,
+  })
+  try {
+    await failing.get_users_id({ id: 1 }, { authorization: 'token' })
+    throw new Error('expected ApiError')
+  } catch (err) {
+    if (!(err instanceof ApiError) || !isApiError<{ id: number }>(err, 'user_exists')) {
+      throw err
+    }
+    if (err.status !== 409 || err.message !== 'taken' || err.details?.id !== 7 || err.type !== 'about:blank') {
+      throw new Error('unexpected ApiError: ' + JSON.stringify(err.problem))
+    }
+  }
+
+  const streamCalls: (string | undefined)[] = []
+  const chunks = [
+    'id: 1\ndata: {"id":1}\n\n:\n\ndata: {"i',
+    'd":2}\r\n\r\nevent: error\ndata: {"status":409,"detail":"gone"}\n\n',
+  ]
+  const streaming = new UsersClient({
+    baseUrl: 'http://example.com',
+    fetch: (async (_url: string, init: RequestInit) => {
+      streamCalls.push((init.headers as Record<string, string>)['Last-Event-ID'])
+      const encoder = new TextEncoder()
+      const body = new ReadableStream<Uint8Array>({
+        start(controller) {
+          for (const chunk of chunks) controller.enqueue(encoder.encode(chunk))
+          controller.close()
+        },
+      })
+      return new Response(body, { status: 200, headers: { 'Content-Type': 'text/event-stream' } })
+    }) as unknown as typeof fetch,
+  })
+  const events: number[] = []
+  try {
+    for await (const ev of streaming.get_users_id_events({ id: 1 }, { lastEventId: '0' })) {
+      events.push(ev.id)
+    }
+    throw new Error('expected ApiError from error event')
+  } catch (err) {
+    if (!(err instanceof ApiError) || err.status !== 409 || err.detail !== 'gone') {
+      throw err
+    }
+  }
+  if (events.join(',') !== '1,2' || streamCalls.join(',') !== '0') {
+    throw new Error('unexpected events: ' + events.join(',') + ' calls: ' + streamCalls.join(','))
+  }
+
+  const lines = ['{"id":1}\n\n{"i', 'd":2}\r\n{"$error":{"status":503,"detail":"down"}}\n']
+  const listing = new UsersClient({
+    baseUrl: 'http://example.com',
+    fetch: (async () => {
+      const encoder = new TextEncoder()
+      const body = new ReadableStream<Uint8Array>({
+        start(controller) {
+          for (const line of lines) controller.enqueue(encoder.encode(line))
+          controller.close()
+        },
+      })
+      return new Response(body, { status: 200, headers: { 'Content-Type': 'application/x-ndjson' } })
+    }) as unknown as typeof fetch,
+  })
+  const items: number[] = []
+  try {
+    for await (const item of listing.get_users_id_items({ id: 1 })) {
+      items.push(item.id)
+    }
+    throw new Error('expected ApiError from error record')
+  } catch (err) {
+    if (!(err instanceof ApiError) || err.status !== 503 || err.detail !== 'down') {
+      throw err
+    }
+  }
+  if (items.join(',') !== '1,2') {
+    throw new Error('unexpected items: ' + items.join(','))
+  }
+
+  const sockets: FakeWebSocket[] = []
+  class FakeWebSocket extends EventTarget {
+    readyState = 0
+    bufferedAmount = 0
+    readonly sent: string[] = []
+    constructor(readonly url: string) {
+      super()
+      sockets.push(this)
+      setTimeout(() => {
+        this.readyState = 1
+        this.dispatchEvent(new Event('open'))
+      })
+    }
+    send(data: string) {
+      this.sent.push(data)
+      const reply = Object.assign(new Event('message'), { data })
+      this.dispatchEvent(reply)
+      const closed = Object.assign(new Event('close'), { code: 4409, reason: '{"status":409,"title":"Conflict"}' })
+      this.readyState = 3
+      this.dispatchEvent(closed)
+    }
+    close() {
+      this.readyState = 3
+    }
+  }
+  const channel = new UsersClient({
+    baseUrl: 'http://example.com',
+    webSocket: FakeWebSocket as unknown as typeof WebSocket,
+  }).get_users_id_socket({ id: 1 })
+  await channel.send({ id: 5 })
+  const received: number[] = []
+  try {
+    for await (const msg of channel) {
+      received.push(msg.id)
+    }
+    throw new Error('expected ApiError from close code')
+  } catch (err) {
+    if (!(err instanceof ApiError) || err.status !== 409) {
+      throw err
+    }
+  }
+  if (received.join(',') !== '5' || sockets[0]?.url !== 'ws://example.com/users/1/socket') {
+    throw new Error('unexpected socket: ' + received.join(',') + ' ' + sockets[0]?.url)
+  }
 }
 
 main().catch((err) => {
@@ -3,6 +3,7 @@ package httprpc
 import (
 	"context"
 	_ "embed"
+	"iter"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -22,7 +23,8 @@ func TestTSClientRuntime(t *testing.T) {
 	}
 
 	type req struct {
-		Q string `json:"q"`
+		Q      string            `json:"q"`
+		Filter map[string]string `json:"filter"`
 	}
 	type meta struct {
 		Authorization string `header:"authorization"`
@@ -35,6 +37,15 @@ func TestTSClientRuntime(t *testing.T) {
 	RegisterHandlerM[req, meta, res](r.EndpointGroup, GETM(func(context.Context, req, meta) (res, error) {
 		return res{ID: 1}, nil
 	}, "/users/:id"))
+	RegisterSSE(r.EndpointGroup, SSE(func(context.Context, struct{}, *SSEStream[res]) error {
+		return nil
+	}, "/users/:id/events"))
+	RegisterNDJSON(r.EndpointGroup, NDJSON(func(context.Context, struct{}) iter.Seq2[res, error] {
+		return nil
+	}, "/users/:id/items"))
+	RegisterWS(r.EndpointGroup, WS(func(context.Context, *WSConn[res, res]) error {
+		return nil
+	}, "/users/:id/socket"))
 
 	outDir := t.TempDir()
 	if err := r.GenTSDir(outDir, TSGenOptions{PackageName: "httprpc-test", ClientName: "API"}); err != nil {
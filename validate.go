@@ -0,0 +1,385 @@
+package httprpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Violation describes a single failed validation rule.
+type Violation struct {
+	Path    string `json:"path"`    // JSON path of the field, or "<source>.<name>" for meta fields
+	Code    string `json:"code"`    // rule that failed: "required", "min", "max", "len", "email", "url" or "invalid"
+	Message string `json:"message"` // human readable description
+}
+
+// ValidationError is returned when a decoded request or meta value fails
+// validation. DefaultCodec encodes it as 422 Unprocessable Entity with the
+// list of violations.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		if v.Path == "" {
+			parts[i] = v.Message
+			continue
+		}
+		parts[i] = v.Path + ": " + v.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Validator is implemented by request and meta types that need validation
+// beyond `validate` tags. Validate runs after the tags pass. Returning a
+// ValidationError reports its violations; any other error is reported as a
+// single violation with code "invalid".
+type Validator interface {
+	Validate() error
+}
+
+// validatePlans caches the validate rules of request and meta types.
+var validatePlans sync.Map // validatePlanKey -> *validatePlan
+
+type validatePlanKey struct {
+	t    reflect.Type
+	meta bool // name top-level fields by their meta tag
+}
+
+type validatePlan struct {
+	fields []validateField
+	err    error
+}
+
+type validateField struct {
+	index  []int
+	name   string
+	rules  []validateRule
+	nested *validatePlan // struct, *struct or []struct fields
+}
+
+type validateRule struct {
+	code string
+	arg  string
+	n    float64
+}
+
+func validatePlanFor(t reflect.Type, meta bool) *validatePlan {
+	key := validatePlanKey{t: t, meta: meta}
+	if p, ok := validatePlans.Load(key); ok {
+		plan, _ := p.(*validatePlan)
+		return plan
+	}
+	p, _ := validatePlans.LoadOrStore(key, compileValidatePlan(t, meta, map[reflect.Type]*validatePlan{}))
+	plan, _ := p.(*validatePlan)
+	return plan
+}
+
+func compileValidatePlan(t reflect.Type, meta bool, seen map[reflect.Type]*validatePlan) *validatePlan {
+	t = deref(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return &validatePlan{}
+	}
+	if p, ok := seen[t]; ok {
+		return p // recursive type; the plan is filled in by the outer call
+	}
+	plan := &validatePlan{}
+	seen[t] = plan
+	if err := plan.addFields(t, nil, "", meta, seen); err != nil {
+		return &validatePlan{err: err}
+	}
+	return plan
+}
+
+func (p *validatePlan) addFields(t reflect.Type, index []int, prefix string, meta bool, seen map[reflect.Type]*validatePlan) error {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && isPromotedJSONEmbed(field) {
+			if err := p.addFields(field.Type, fieldIndex, prefix, meta, seen); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		rules, err := parseValidateTag(t, field)
+		if err != nil {
+			return err
+		}
+		var nested *validatePlan
+		if st := validateDiveType(field.Type); st != nil {
+			nested = compileValidatePlan(st, false, seen)
+			if nested.err != nil {
+				return nested.err
+			}
+		}
+		if rules == nil && nested == nil {
+			continue
+		}
+		p.fields = append(p.fields, validateField{
+			index:  fieldIndex,
+			name:   prefix + validateFieldName(field, meta),
+			rules:  rules,
+			nested: nested,
+		})
+	}
+	return nil
+}
+
+// validateDiveType returns the struct type validation descends into for a
+// field of type t: T, *T, []T and []*T where T is a non-scalar struct.
+func validateDiveType(t reflect.Type) reflect.Type {
+	t = deref(t)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = deref(t.Elem())
+	}
+	if t.Kind() != reflect.Struct || isQueryScalar(t) {
+		return nil
+	}
+	return t
+}
+
+func validateFieldName(f reflect.StructField, meta bool) string {
+	if meta {
+		for _, s := range metaTagSources {
+			if name, found, skip := tagName(f, s.key); found && !skip && name != "" {
+				return s.key + "." + name
+			}
+		}
+	}
+	if name, found, skip := tagName(f, "json"); found && !skip && name != "" {
+		return name
+	}
+	return f.Name
+}
+
+func parseValidateTag(owner reflect.Type, f reflect.StructField) ([]validateRule, error) {
+	tag, ok := f.Tag.Lookup("validate")
+	if !ok || tag == "" || tag == "-" {
+		return nil, nil
+	}
+	kind := deref(f.Type).Kind()
+	var rules []validateRule
+	for _, part := range strings.Split(tag, ",") {
+		code, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		rule := validateRule{code: code, arg: arg}
+		switch code {
+		case "required", "omitempty":
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: validate rule %s needs a number, got %q", metaOwnerName(owner), f.Name, code, arg)
+			}
+			if !isNumberKind(kind) && !hasLen(kind) {
+				return nil, fmt.Errorf("%s.%s: validate rule %s does not apply to %s", metaOwnerName(owner), f.Name, code, kind)
+			}
+			if code == "len" && !hasLen(kind) {
+				return nil, fmt.Errorf("%s.%s: validate rule len does not apply to %s", metaOwnerName(owner), f.Name, kind)
+			}
+			rule.n = n
+		case "email", "url":
+			if kind != reflect.String {
+				return nil, fmt.Errorf("%s.%s: validate rule %s needs a string field", metaOwnerName(owner), f.Name, code)
+			}
+		default:
+			return nil, fmt.Errorf("%s.%s: unknown validate rule %q", metaOwnerName(owner), f.Name, code)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasLen(k reflect.Kind) bool {
+	return k == reflect.String || k == reflect.Slice || k == reflect.Map || k == reflect.Array
+}
+
+// validate appends the violations of rv, whose fields are named under prefix.
+func (p *validatePlan) validate(rv reflect.Value, prefix string, out []Violation) []Violation {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return out
+		}
+		rv = rv.Elem()
+	}
+	for _, f := range p.fields {
+		v := rv.FieldByIndex(f.index)
+		path := prefix + f.name
+		out = checkRules(v, path, f.rules, out)
+		if f.nested != nil {
+			out = f.nested.validateNested(v, path, out)
+		}
+	}
+	return out
+}
+
+func (p *validatePlan) validateNested(v reflect.Value, path string, out []Violation) []Violation {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return out
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := range v.Len() {
+			out = p.validateNested(v.Index(i), path+"["+strconv.Itoa(i)+"]", out)
+		}
+		return out
+	}
+	return p.validate(v, path+".", out)
+}
+
+func checkRules(v reflect.Value, path string, rules []validateRule, out []Violation) []Violation {
+	if len(rules) == 0 {
+		return out
+	}
+	for _, rule := range rules {
+		if rule.code == "omitempty" && v.IsZero() {
+			return out
+		}
+	}
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	for _, rule := range rules {
+		if msg := checkRule(v, rule); msg != "" {
+			out = append(out, Violation{Path: path, Code: rule.code, Message: msg})
+			if rule.code == "required" {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// checkRule returns a message describing why v fails rule, or "" if it passes.
+func checkRule(v reflect.Value, rule validateRule) string {
+	if v.Kind() == reflect.Pointer {
+		if rule.code == "required" {
+			return "is required"
+		}
+		return "" // nil pointers only fail required
+	}
+	switch rule.code {
+	case "required":
+		if v.IsZero() {
+			return "is required"
+		}
+	case "min", "max", "len":
+		n, isLen := ruleOperand(v)
+		switch {
+		case rule.code == "min" && n < rule.n:
+			return boundMessage("at least", rule.arg, isLen, v.Kind())
+		case rule.code == "max" && n > rule.n:
+			return boundMessage("at most", rule.arg, isLen, v.Kind())
+		case rule.code == "len" && n != rule.n:
+			return boundMessage("exactly", rule.arg, isLen, v.Kind())
+		}
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		if err != nil || addr.Address != v.String() {
+			return "must be a valid email address"
+		}
+	case "url":
+		u, err := url.ParseRequestURI(v.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be an absolute URL"
+		}
+	}
+	return ""
+}
+
+// ruleOperand returns the number min/max/len compare against: the value of
+// numbers, the rune count of strings and the length of collections.
+func ruleOperand(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	default:
+		return 0, false
+	}
+}
+
+func boundMessage(bound, arg string, isLen bool, kind reflect.Kind) string {
+	switch {
+	case !isLen:
+		return "must be " + bound + " " + arg
+	case kind == reflect.String:
+		return "must be " + bound + " " + arg + " characters long"
+	default:
+		return "must contain " + bound + " " + arg + " items"
+	}
+}
+
+// validateValue checks v's validate tags and then its Validate method.
+// meta selects meta-style field paths ("header.x-request-id").
+func validateValue[T any](v *T, meta bool) []Violation {
+	plan := validatePlanFor(reflect.TypeFor[T](), meta)
+	var out []Violation
+	if plan.err == nil && len(plan.fields) > 0 {
+		out = plan.validate(reflect.ValueOf(v).Elem(), "", nil)
+	}
+	if len(out) > 0 {
+		return out
+	}
+
+	var validator Validator
+	switch {
+	case reflect.TypeFor[T]().Implements(validatorType):
+		// A null body decodes to a nil pointer, which has nothing to validate.
+		if rv := reflect.ValueOf(v).Elem(); (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil() {
+			return nil
+		}
+		validator, _ = any(*v).(Validator)
+	case reflect.TypeFor[*T]().Implements(validatorType):
+		validator, _ = any(v).(Validator)
+	default:
+		return nil
+	}
+	err := validator.Validate()
+	if err == nil {
+		return nil
+	}
+	var ve ValidationError
+	if errors.As(err, &ve) {
+		return ve.Violations
+	}
+	return []Violation{{Code: "invalid", Message: err.Error()}}
+}
+
+var validatorType = reflect.TypeFor[Validator]()
+
+// validationFailure wraps violations for the codec: 422 with the violations.
+func validationFailure(violations []Violation) error {
+	return StatusError{Status: http.StatusUnprocessableEntity, Err: ValidationError{Violations: violations}}
+}
@@ -0,0 +1,211 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateSignup struct {
+	Email     string            `json:"email" validate:"required,email"`
+	Name      string            `json:"name" validate:"min=2,max=20"`
+	Age       int               `json:"age" validate:"omitempty,min=18"`
+	Website   string            `json:"website" validate:"omitempty,url"`
+	Tags      []string          `json:"tags" validate:"max=2"`
+	Addresses []validateAddress `json:"addresses"`
+	Billing   *validateAddress  `json:"billing"`
+}
+
+type validateMeta struct {
+	Tenant string `header:"x-tenant,omitempty" validate:"required"`
+}
+
+type validateRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (r validateRange) Validate() error {
+	if r.From > r.To {
+		return ValidationError{Violations: []Violation{{Path: "to", Code: "range", Message: "must not be before from"}}}
+	}
+	return nil
+}
+
+func TestValidateValue_TagsAndNestedPaths(t *testing.T) {
+	req := validateSignup{
+		Email:     "not-an-email",
+		Name:      "a",
+		Tags:      []string{"a", "b", "c"},
+		Addresses: []validateAddress{{City: "Paris"}, {}},
+		Billing:   &validateAddress{},
+	}
+
+	got := validateValue(&req, false)
+	want := []Violation{
+		{Path: "email", Code: "email", Message: "must be a valid email address"},
+		{Path: "name", Code: "min", Message: "must be at least 2 characters long"},
+		{Path: "tags", Code: "max", Message: "must contain at most 2 items"},
+		{Path: "addresses[1].city", Code: "required", Message: "is required"},
+		{Path: "billing.city", Code: "required", Message: "is required"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected violations:\n got %+v\nwant %+v", got, want)
+	}
+
+	ok := validateSignup{Email: "a@example.com", Name: "Ann", Website: "https://example.com"}
+	if got := validateValue(&ok, false); len(got) != 0 {
+		t.Fatalf("expected no violations, got %+v", got)
+	}
+}
+
+func TestValidateValue_ValidatorInterface(t *testing.T) {
+	got := validateValue(&validateRange{From: 2, To: 1}, false)
+	if len(got) != 1 || got[0].Code != "range" {
+		t.Fatalf("unexpected violations: %+v", got)
+	}
+}
+
+func TestValidateValue_NilPointerValidator(t *testing.T) {
+	var req *validateRange
+	if got := validateValue(&req, false); len(got) != 0 {
+		t.Fatalf("expected no violations for a nil request, got %+v", got)
+	}
+
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(_ context.Context, req *validateRange) (struct{}, error) {
+		if req != nil {
+			return struct{}{}, errors.New("expected a nil request")
+		}
+		return struct{}{}, nil
+	}, "/range"))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/range", strings.NewReader("null")))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d for a null body, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+}
+
+type pointerSignup struct {
+	Email string `json:"email" validate:"required"`
+	Plan  string `json:"plan" default:"free" enum:"free,pro"`
+}
+
+func TestRouterHandler_PointerRequestPlans(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(_ context.Context, req *pointerSignup) (string, error) {
+		return req.Plan, nil
+	}, "/signup"))
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		body string
+		code int
+		want string
+	}{
+		{body: `{"plan":"pro"}`, code: http.StatusUnprocessableEntity, want: `"path":"email","code":"required"`},
+		{body: `{"email":"a@example.com","plan":"gold"}`, code: http.StatusBadRequest, want: "plan"},
+		{body: `{"email":"a@example.com"}`, code: http.StatusOK, want: `"free"`},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(tt.body)))
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Fatalf("%s: expected %d containing %q, got %d %s", tt.body, tt.code, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestRouterHandler_ValidationReturns422(t *testing.T) {
+	r := New()
+	RegisterHandlerM(r.EndpointGroup, POSTM(func(context.Context, validateSignup, validateMeta) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/signup"))
+
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(`{"email":"a@example.com","name":"x"}`)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d, got %d: %s", http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Violations []Violation `json:"violations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := []Violation{
+		{Path: "name", Code: "min", Message: "must be at least 2 characters long"},
+		{Path: "header.x-tenant", Code: "required", Message: "is required"},
+	}
+	if !reflect.DeepEqual(body.Violations, want) {
+		t.Fatalf("unexpected violations: %+v", body.Violations)
+	}
+}
+
+func TestDefaultCodecEncodeError_ValidationErrorWithoutStatus(t *testing.T) {
+	codec := DefaultCodec[struct{}, struct{}]{}
+	rec := httptest.NewRecorder()
+
+	_ = codec.EncodeError(rec, ValidationError{Violations: []Violation{{Code: "invalid", Message: "bad"}}})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestRegisterHandler_InvalidValidateTag(t *testing.T) {
+	type Req struct {
+		Count int `json:"count" validate:"email"`
+	}
+
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, Req) (struct{}, error) {
+		return struct{}{}, nil
+	}, "/items"))
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), "validate rule email needs a string field") {
+		t.Fatalf("expected validate tag error, got %v", err)
+	}
+}
+
+func TestRouterGenTS_EmitsValidateConstraints(t *testing.T) {
+	r := New()
+	RegisterHandler(r.EndpointGroup, POST(func(context.Context, validateSignup) (struct{}, error) {
+		return struct{}{}, errors.New("unused")
+	}, "/signup"))
+
+	var buf bytes.Buffer
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"  /** @format email */\n  email: string\n",
+		"  /**\n   * @minLength 2\n   * @maxLength 20\n   */\n  name: string\n",
+		"  /** @minimum 18 */\n  age: number\n",
+		"  /** @maxItems 2 */\n  tags: string[]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, out)
+		}
+	}
+}
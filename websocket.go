@@ -0,0 +1,716 @@
+package httprpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec // RFC 6455 mandates SHA-1 for Sec-WebSocket-Accept
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// WSHandler serves one WebSocket connection. ctx is cancelled when the client
+// closes the connection or it fails. Returning closes the connection: nil
+// with WSCloseNormal, a *WSCloseError with its code, and any other error with
+// 4000 plus the status of its problem details (see WSCloseError).
+type WSHandler[In, Out any] func(ctx context.Context, conn *WSConn[In, Out]) error
+
+// WSEndpoint is a GET endpoint that upgrades to a WebSocket exchanging In
+// messages from the client and Out messages from the server.
+type WSEndpoint[In, Out any] struct {
+	Handler WSHandler[In, Out]
+	Path    string
+	// Codec decodes incoming messages with DecodeBody and encodes outgoing
+	// ones with Encode. Nil means DefaultCodec, which sends JSON text
+	// messages; codecs producing other content types send binary messages.
+	Codec Codec[In, Out]
+}
+
+// WS creates a WSEndpoint using DefaultCodec.
+func WS[In, Out any](handler WSHandler[In, Out], path string) WSEndpoint[In, Out] {
+	return WSEndpoint[In, Out]{Handler: handler, Path: path}
+}
+
+// DefaultWSReadLimit is the largest incoming WebSocket message accepted
+// unless WithReadLimit says otherwise.
+const DefaultWSReadLimit = 1 << 20
+
+// wsControlTimeout bounds control frame writes and how long a closing
+// connection waits for the client's close frame.
+const wsControlTimeout = 5 * time.Second
+
+// wsMinPongTimeout is how long a ping may go unanswered at least, even with
+// a shorter heartbeat, since the client first reads the messages queued
+// ahead of it.
+const wsMinPongTimeout = time.Second
+
+// WSCloseCode is a WebSocket close status code (RFC 6455 section 7.4).
+type WSCloseCode uint16
+
+// Close codes used by WSConn. Handler errors close with 4000 plus the HTTP
+// status of their problem details, e.g. 4404 or 4500.
+const (
+	WSCloseNormal          WSCloseCode = 1000
+	WSCloseGoingAway       WSCloseCode = 1001
+	WSCloseProtocolError   WSCloseCode = 1002
+	WSCloseUnsupportedData WSCloseCode = 1003
+	// WSCloseNoStatus is reported for close frames without a code. It is
+	// never sent.
+	WSCloseNoStatus        WSCloseCode = 1005
+	WSCloseInvalidPayload  WSCloseCode = 1007
+	WSClosePolicyViolation WSCloseCode = 1008
+	WSCloseMessageTooBig   WSCloseCode = 1009
+	WSCloseInternalError   WSCloseCode = 1011
+)
+
+// WSCloseError is a close frame. WSConn.Receive returns one when the client
+// closes the connection or breaks the protocol, and handlers return one to
+// close with a specific code. Reason must fit in 123 bytes.
+type WSCloseError struct {
+	Code   WSCloseCode
+	Reason string
+}
+
+func (e *WSCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+var (
+	// errWSClosed is returned by sends after a close frame was sent.
+	errWSClosed = errors.New("websocket: connection closed")
+	// errWSPingTimeout fails connections that did not answer a ping.
+	errWSPingTimeout = errors.New("websocket: ping timed out")
+)
+
+// WebSocket opcodes (RFC 6455 section 5.2).
+const (
+	wsOpContinuation byte = 0x0
+	wsOpText         byte = 0x1
+	wsOpBinary       byte = 0x2
+	wsOpClose        byte = 0x8
+	wsOpPing         byte = 0x9
+	wsOpPong         byte = 0xa
+)
+
+// WSConn is a typed WebSocket connection. Receive must not be called
+// concurrently; the other methods are safe for concurrent use.
+//
+// Send blocks until the message is handed to the network, so a client that
+// reads slowly slows the handler down instead of growing a queue. Likewise a
+// handler that stops calling Receive stops reading from the network.
+type WSConn[In, Out any] struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	codec       Codec[In, Out]
+	contentType string // given to the codec for incoming messages
+	dataOp      byte   // opcode of outgoing messages
+	readLimit   int64
+
+	messages chan []byte   // complete data messages from the read loop
+	stopped  chan struct{} // closed once the handler returned
+	readDone chan struct{} // closed when the read loop exits
+	readErr  error         // why the read loop exited; set before readDone closes
+	// pingSentAt is when the unanswered ping was sent, in Unix nanoseconds,
+	// or zero. Any frame from the client clears it.
+	pingSentAt  atomic.Int64
+	pongTimeout time.Duration
+
+	failOnce sync.Once
+	failErr  error
+	cancel   context.CancelCauseFunc
+
+	writeLock chan struct{} // holds a token while a frame is written
+	bw        *bufio.Writer
+	closeSent bool
+}
+
+// Receive waits for the next message and decodes it with the endpoint's
+// codec. Invalid messages are reported as 400 or 422 errors like request
+// bodies; the connection stays usable. Once the client closed the connection,
+// Receive returns a *WSCloseError.
+func (c *WSConn[In, Out]) Receive(ctx context.Context) (In, error) {
+	var in In
+	select {
+	case data := <-c.messages:
+		return c.decode(ctx, data)
+	case <-c.readDone:
+		return in, c.readErr
+	case <-ctx.Done():
+		return in, ctx.Err() //nolint:wrapcheck // callers check for context errors
+	}
+}
+
+func (c *WSConn[In, Out]) decode(ctx context.Context, data []byte) (In, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(data))
+	if err != nil {
+		var in In
+		return in, fmt.Errorf("websocket: decode message: %w", err)
+	}
+	r.Header.Set("Content-Type", c.contentType)
+	in, err := c.codec.DecodeBody(r)
+	if err != nil {
+		return in, decodeFailure(err)
+	}
+	if violations := validateValue(&in, false); len(violations) > 0 {
+		return in, validationFailure(violations)
+	}
+	return in, nil
+}
+
+// Send encodes out with the endpoint's codec and sends it as one message. It
+// returns once the message is written or ctx is done. A send interrupted by
+// ctx while writing fails the connection, since part of the message may have
+// been sent.
+func (c *WSConn[In, Out]) Send(ctx context.Context, out Out) error {
+	buf := &wsMessageWriter{header: http.Header{}}
+	if err := c.codec.Encode(buf, out); err != nil {
+		return fmt.Errorf("websocket: encode message: %w", err)
+	}
+	data := buf.body.Bytes()
+	if c.dataOp == wsOpText {
+		data = bytes.TrimSuffix(data, []byte("\n"))
+	}
+	return c.writeFrame(ctx, c.dataOp, data)
+}
+
+// Close sends a close frame. Messages sent by the client before it answers
+// can still be received; Receive then returns the client's *WSCloseError.
+func (c *WSConn[In, Out]) Close(code WSCloseCode, reason string) error {
+	return c.writeClose(code, reason)
+}
+
+func (c *WSConn[In, Out]) writeClose(code WSCloseCode, reason string) error {
+	var payload []byte
+	if code != WSCloseNoStatus {
+		payload = binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, truncateUTF8(reason, 123)...)
+	}
+	return c.writeControl(wsOpClose, payload)
+}
+
+func (c *WSConn[In, Out]) writeControl(op byte, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), wsControlTimeout)
+	defer cancel()
+	return c.writeFrame(ctx, op, payload)
+}
+
+// heartbeat sends a ping unless one is still unanswered, and fails the
+// connection once that ping is older than pongTimeout. A ping waits for a
+// write in progress; if that write is stuck for wsControlTimeout the client
+// is not reading and the connection fails too.
+func (c *WSConn[In, Out]) heartbeat() {
+	if sent := c.pingSentAt.Load(); sent != 0 {
+		if time.Since(time.Unix(0, sent)) >= c.pongTimeout {
+			c.fail(errWSPingTimeout)
+		}
+		return
+	}
+	// Mark the ping before sending it, so that an early answer is not lost.
+	queued := time.Now().UnixNano()
+	c.pingSentAt.Store(queued)
+	switch err := c.writeControl(wsOpPing, nil); {
+	case err == nil:
+		c.pingSentAt.CompareAndSwap(queued, time.Now().UnixNano())
+	case errors.Is(err, errWSClosed):
+		c.pingSentAt.Store(0)
+	default:
+		c.fail(errWSPingTimeout)
+	}
+}
+
+// writeFrame writes one frame. Waiting for another write to finish ends
+// with ctx without affecting the connection.
+func (c *WSConn[In, Out]) writeFrame(ctx context.Context, op byte, payload []byte) error {
+	select {
+	case c.writeLock <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("websocket: write: %w", ctx.Err())
+	}
+	defer func() { <-c.writeLock }()
+
+	if c.closeSent {
+		return errWSClosed
+	}
+	if op == wsOpClose {
+		c.closeSent = true
+	}
+
+	// Interrupt a blocked write when ctx ends.
+	fired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetWriteDeadline(time.Unix(1, 0))
+		close(fired)
+	})
+
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = binary.BigEndian.AppendUint16(append(header, 126), uint16(n))
+	default:
+		header = binary.BigEndian.AppendUint64(append(header, 127), uint64(n))
+	}
+	_, err := c.bw.Write(header)
+	if err == nil {
+		_, err = c.bw.Write(payload)
+	}
+	if err == nil {
+		err = c.bw.Flush()
+	}
+
+	if !stop() {
+		<-fired
+		_ = c.conn.SetWriteDeadline(time.Time{})
+		if err != nil {
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("websocket: write: %w", err)
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+// fail tears the connection down without a closing handshake.
+func (c *WSConn[In, Out]) fail(err error) {
+	c.failOnce.Do(func() {
+		c.failErr = err
+		c.cancel(err)
+		_ = c.conn.Close()
+	})
+}
+
+// readLoop reads frames until the client closes the connection or it fails,
+// answering pings and handing complete messages to Receive.
+func (c *WSConn[In, Out]) readLoop() {
+	defer close(c.readDone)
+	var (
+		message []byte
+		op      byte // opcode of the message being assembled; 0 if none
+	)
+	for {
+		fin, frameOp, payload, err := c.readFrame()
+		if err != nil {
+			c.readErr = c.readFailure(err)
+			return
+		}
+		c.pingSentAt.Store(0)
+
+		switch frameOp {
+		case wsOpPing:
+			_ = c.writeControl(wsOpPong, payload)
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			c.readErr = c.peerClosed(payload)
+			return
+		case wsOpText, wsOpBinary:
+			if op != 0 {
+				c.readErr = c.protocolFailure(WSCloseProtocolError, "expected a continuation frame")
+				return
+			}
+			op = frameOp
+		case wsOpContinuation:
+			if op == 0 {
+				c.readErr = c.protocolFailure(WSCloseProtocolError, "unexpected continuation frame")
+				return
+			}
+		}
+
+		if int64(len(message))+int64(len(payload)) > c.readLimit {
+			c.readErr = c.protocolFailure(WSCloseMessageTooBig, "message too big")
+			return
+		}
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if op == wsOpText && !utf8.Valid(message) {
+			c.readErr = c.protocolFailure(WSCloseInvalidPayload, "invalid UTF-8")
+			return
+		}
+		select {
+		case c.messages <- message:
+		case <-c.stopped:
+			// The handler returned; drop messages until the client's close frame.
+		}
+		message, op = nil, 0
+	}
+}
+
+func (c *WSConn[In, Out]) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err //nolint:wrapcheck // wrapped by readFailure
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0f
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, &WSCloseError{Code: WSCloseProtocolError, Reason: "reserved bits set"}
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, &WSCloseError{Code: WSCloseProtocolError, Reason: "unmasked client frame"}
+	}
+	control := op&0x8 != 0
+	switch op {
+	case wsOpContinuation, wsOpText, wsOpBinary, wsOpClose, wsOpPing, wsOpPong:
+	default:
+		return false, 0, nil, &WSCloseError{Code: WSCloseProtocolError, Reason: "unknown opcode " + strconv.Itoa(int(op))}
+	}
+
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err //nolint:wrapcheck // wrapped by readFailure
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err //nolint:wrapcheck // wrapped by readFailure
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if control && (!fin || n > 125) {
+		return false, 0, nil, &WSCloseError{Code: WSCloseProtocolError, Reason: "invalid control frame"}
+	}
+	if n > uint64(c.readLimit) {
+		return false, 0, nil, &WSCloseError{Code: WSCloseMessageTooBig, Reason: "message too big"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err //nolint:wrapcheck // wrapped by readFailure
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err //nolint:wrapcheck // wrapped by readFailure
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// readFailure reports why reading stopped: a protocol violation is answered
+// with a close frame, anything else fails the connection.
+func (c *WSConn[In, Out]) readFailure(err error) error {
+	var ce *WSCloseError
+	if errors.As(err, &ce) {
+		return c.protocolFailure(ce.Code, ce.Reason)
+	}
+	c.fail(fmt.Errorf("websocket: read: %w", err))
+	return c.failErr
+}
+
+func (c *WSConn[In, Out]) protocolFailure(code WSCloseCode, reason string) error {
+	ce := &WSCloseError{Code: code, Reason: reason}
+	_ = c.writeClose(code, reason)
+	c.cancel(ce)
+	return ce
+}
+
+// peerClosed answers the client's close frame with the same code.
+func (c *WSConn[In, Out]) peerClosed(payload []byte) error {
+	ce := &WSCloseError{Code: WSCloseNoStatus}
+	if len(payload) == 1 {
+		return c.protocolFailure(WSCloseProtocolError, "invalid close frame")
+	}
+	if len(payload) >= 2 {
+		ce.Code = WSCloseCode(binary.BigEndian.Uint16(payload))
+		ce.Reason = string(payload[2:])
+		if !validCloseCode(ce.Code) || !utf8.ValidString(ce.Reason) {
+			return c.protocolFailure(WSCloseProtocolError, "invalid close frame")
+		}
+	}
+	_ = c.writeClose(ce.Code, "")
+	c.cancel(ce)
+	return ce
+}
+
+// validCloseCode reports whether a client may send code (RFC 6455 section
+// 7.4.1).
+func validCloseCode(code WSCloseCode) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	default:
+		return code >= 3000 && code <= 4999
+	}
+}
+
+// wsCloseFor chooses the close frame for a handler's return value.
+func wsCloseFor(err error) *WSCloseError {
+	if err == nil {
+		return &WSCloseError{Code: WSCloseNormal}
+	}
+	var ce *WSCloseError
+	if errors.As(err, &ce) {
+		return ce
+	}
+	p := problemFor(err)
+	return &WSCloseError{Code: WSCloseCode(4000 + p.Status), Reason: closeReason(p)}
+}
+
+// closeReason encodes the problem as JSON, dropping members until it fits in
+// a close frame.
+func closeReason(p *Problem) string {
+	reason := map[string]any{"status": p.Status, "title": p.Title}
+	if code, ok := p.Extensions["code"]; ok {
+		reason["code"] = code
+	}
+	if p.Detail != "" {
+		reason["detail"] = p.Detail
+	}
+	for _, drop := range []string{"detail", "code", "title"} {
+		if data, err := json.Marshal(reason); err == nil && len(data) <= 123 {
+			return string(data)
+		}
+		delete(reason, drop)
+	}
+	return `{"status":` + strconv.Itoa(p.Status) + `}`
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// wsMessageWriter collects what a codec encodes for one message.
+type wsMessageWriter struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *wsMessageWriter) Header() http.Header { return w.header }
+
+func (w *wsMessageWriter) Write(p []byte) (int, error) { return w.body.Write(p) }
+
+func (w *wsMessageWriter) WriteHeader(int) {}
+
+// RegisterWS registers a WebSocket endpoint with the endpoint group. The
+// connection is pinged every heartbeat interval and fails when a ping goes
+// unanswered; see WithHeartbeat, WithReadLimit and WithAllowedOrigins.
+//
+// The handshake hijacks the connection, so WebSockets need HTTP/1.1 and
+// middleware whose response writers implement Unwrap.
+func RegisterWS[In, Out any](eg *EndpointGroup, in WSEndpoint[In, Out], opts ...StreamOption) {
+	o := newStreamOptions(opts)
+	path := eg.Prefix + in.Path
+	if err := compileRequestPlans(reflect.TypeFor[In]()); err != nil {
+		eg.registerError(fmt.Errorf("register %s %s: invalid message %s: %w", http.MethodGet, path, reflect.TypeFor[In](), err))
+		return
+	}
+
+	codec := in.Codec
+	if codec == nil {
+		codec = DefaultCodec[In, Out]{}
+	}
+	consumes, produces := []string{"application/json"}, []string{"application/json"}
+	if ct, ok := any(codec).(interface {
+		Consumes() []string
+		Produces() []string
+	}); ok {
+		consumes = ct.Consumes()
+		produces = ct.Produces()
+	}
+
+	eg.addEndpoint(&endpoint{
+		Path:       path,
+		Method:     http.MethodGet,
+		Source:     funcSource(in.Handler),
+		Handler:    adaptWSHandler(in.Handler, codec, consumes, produces, o, eg.handlerConfig()),
+		Group:      eg,
+		NoAutoHead: true,
+	}, &EndpointMeta{
+		Name:     o.name,
+		Method:   http.MethodGet,
+		Path:     path,
+		Host:     eg.match.hostPattern(),
+		Headers:  eg.match.headerMap(),
+		Req:      reflect.TypeFor[In](),
+		Res:      reflect.TypeFor[Out](),
+		Consumes: consumes,
+		Produces: produces,
+		Stream:   StreamWebSocket,
+	})
+}
+
+func adaptWSHandler[In, Out any](handler WSHandler[In, Out], codec Codec[In, Out], consumes, produces []string, o streamOptions, cfg handlerConfig) http.Handler {
+	contentType := firstOr(consumes)
+	dataOp := wsOpBinary
+	if isJSONContentType(firstOr(produces)) {
+		dataOp = wsOpText
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		netConn, brw, err := wsUpgrade(w, r, o.allowedOrigins)
+		if errors.Is(err, errWSClosed) {
+			return
+		}
+		if err != nil {
+			codec := DefaultCodec[In, Out]{}
+			if encodeErr := codec.EncodeError(w, err); encodeErr != nil {
+				slog.Error("failed to encode error response", "error", encodeErr)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		conn := &WSConn[In, Out]{
+			conn:        netConn,
+			br:          brw.Reader,
+			bw:          brw.Writer,
+			codec:       codec,
+			contentType: contentType,
+			dataOp:      dataOp,
+			readLimit:   o.readLimit,
+			messages:    make(chan []byte),
+			stopped:     make(chan struct{}),
+			readDone:    make(chan struct{}),
+			writeLock:   make(chan struct{}, 1),
+			pongTimeout: max(o.heartbeat, wsMinPongTimeout),
+			cancel:      cancel,
+		}
+		go conn.readLoop()
+
+		stopPing := startTicker(ctx, o.heartbeat, conn.heartbeat)
+		err = handler(ctx, conn)
+		stopPing()
+		if err != nil && ctx.Err() == nil {
+			err = cfg.handlerError(r, err)
+		} else if ctx.Err() != nil {
+			err = nil
+		}
+		ce := wsCloseFor(err)
+		_ = conn.writeClose(ce.Code, ce.Reason)
+
+		// Wait for the client's close frame before closing the connection.
+		close(conn.stopped)
+		_ = netConn.SetReadDeadline(time.Now().Add(wsControlTimeout))
+		<-conn.readDone
+		_ = netConn.Close()
+	})
+}
+
+// wsUpgrade performs the server side of the opening handshake (RFC 6455
+// section 4.2) and hijacks the connection.
+func wsUpgrade(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (net.Conn, *bufio.ReadWriter, error) {
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		return nil, nil, StatusError{Status: http.StatusUpgradeRequired, Err: errors.New("websocket: expected an upgrade request")}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, nil, StatusError{Status: http.StatusUpgradeRequired, Err: errors.New("websocket: unsupported version")}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, nil, StatusError{Status: http.StatusBadRequest, Err: errors.New("websocket: invalid Sec-WebSocket-Key")}
+	}
+	if !originAllowed(r, allowedOrigins) {
+		return nil, nil, StatusError{Status: http.StatusForbidden, Err: errors.New("websocket: origin not allowed")}
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, nil, StatusError{Status: http.StatusInternalServerError, Err: fmt.Errorf("websocket: %w", err)}
+	}
+	// Connections outlive the server's ReadTimeout and WriteTimeout.
+	_ = netConn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11")) //nolint:gosec // see import
+	_, err = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err == nil {
+		err = brw.Flush()
+	}
+	if err != nil {
+		_ = netConn.Close()
+		// The connection is hijacked, so no error response can be written.
+		return nil, nil, fmt.Errorf("%w: write handshake: %w", errWSClosed, err)
+	}
+	return netConn, brw, nil
+}
+
+// headerHasToken reports whether the comma-separated header contains token,
+// ignoring case.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for t := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// originAllowed protects against cross-site WebSocket hijacking. Requests
+// without an Origin header come from non-browser clients and are allowed.
+// Without patterns the origin's host must equal the request's host; otherwise
+// it must match one of the path.Match patterns, e.g. "*.example.com".
+func originAllowed(r *http.Request, patterns []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if len(patterns) == 0 {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(u.Host)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONContentType reports whether ct is application/json or a +json type.
+func isJSONContentType(ct string) bool {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,361 @@
+package httprpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type editIn struct {
+	Op   string `json:"op" validate:"required"`
+	Text string `json:"text"`
+}
+
+type editOut struct {
+	Doc  string `json:"doc"`
+	Text string `json:"text"`
+}
+
+var errDocLocked = errors.New("document locked")
+
+func editHandler(ctx context.Context, conn *WSConn[editIn, editOut]) error {
+	doc, _ := PathParam(ctx, "id")
+	for {
+		in, err := conn.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		switch in.Op {
+		case "lock":
+			return errDocLocked
+		case "bye":
+			return nil
+		}
+		if err := conn.Send(ctx, editOut{Doc: doc, Text: strings.ToUpper(in.Text)}); err != nil {
+			return err
+		}
+	}
+}
+
+func newWSServer(t *testing.T, handler WSHandler[editIn, editOut], opts ...StreamOption) *httptest.Server {
+	t.Helper()
+	r := New(WithRedactedErrors())
+	RegisterWS(r.EndpointGroup, WS(handler, "/docs/:id/edit"), opts...)
+	r.MapError(errDocLocked, http.StatusConflict, "locked")
+	h, err := r.Handler()
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// wsClient is a minimal client side of RFC 6455 for tests.
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, srv *httptest.Server, path string, header http.Header) (*wsClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	return &wsClient{t: t, conn: conn, br: br}, res
+}
+
+func (c *wsClient) writeFrame(fin bool, op byte, payload []byte, masked bool) {
+	c.t.Helper()
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	default:
+		frame = binary.BigEndian.AppendUint16(append(frame, maskBit|126), uint16(n))
+	}
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("write frame: %v", err)
+	}
+}
+
+func (c *wsClient) send(op byte, payload string) {
+	c.writeFrame(true, op, []byte(payload), true)
+}
+
+func (c *wsClient) closeWith(code WSCloseCode) {
+	c.send(wsOpClose, string(binary.BigEndian.AppendUint16(nil, uint16(code))))
+}
+
+func (c *wsClient) read() (byte, string) {
+	c.t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		c.t.Fatalf("read frame: %v", err)
+	}
+	if head[1]&0x80 != 0 {
+		c.t.Fatal("server frames must not be masked")
+	}
+	n := int(head[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		_, _ = io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatalf("read payload: %v", err)
+	}
+	return head[0] & 0x0f, string(payload)
+}
+
+func (c *wsClient) readClose() (WSCloseCode, string) {
+	c.t.Helper()
+	op, payload := c.read()
+	if op != wsOpClose || len(payload) < 2 {
+		c.t.Fatalf("expected a close frame, got opcode %d %q", op, payload)
+	}
+	return WSCloseCode(binary.BigEndian.Uint16([]byte(payload))), payload[2:]
+}
+
+func TestWS_ExchangesTypedMessages(t *testing.T) {
+	srv := newWSServer(t, editHandler)
+	c, res := dialWS(t, srv, "/docs/7/edit", nil)
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake %d %v", res.StatusCode, res.Header)
+	}
+
+	c.send(wsOpText, `{"op":"insert","text":"hi"}`)
+	if op, msg := c.read(); op != wsOpText || msg != `{"doc":"7","text":"HI"}` {
+		t.Fatalf("unexpected message %d %q", op, msg)
+	}
+
+	// A fragmented message with a ping in between.
+	c.writeFrame(false, wsOpText, []byte(`{"op":"insert",`), true)
+	c.send(wsOpPing, "p1")
+	c.writeFrame(true, wsOpContinuation, []byte(`"text":"yo"}`), true)
+	if op, msg := c.read(); op != wsOpPong || msg != "p1" {
+		t.Fatalf("expected a pong, got %d %q", op, msg)
+	}
+	if _, msg := c.read(); msg != `{"doc":"7","text":"YO"}` {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	c.closeWith(WSCloseGoingAway)
+	if code, _ := c.readClose(); code != WSCloseGoingAway {
+		t.Fatalf("expected the close code echoed, got %d", code)
+	}
+}
+
+func TestWS_CloseCodes(t *testing.T) {
+	srv := newWSServer(t, editHandler, WithReadLimit(64))
+
+	for _, tc := range []struct {
+		name   string
+		send   func(c *wsClient)
+		code   WSCloseCode
+		reason string
+	}{
+		{"handler returns nil", func(c *wsClient) { c.send(wsOpText, `{"op":"bye"}`) }, WSCloseNormal, ""},
+		{"mapped error", func(c *wsClient) { c.send(wsOpText, `{"op":"lock"}`) }, 4409, `{"code":"locked","detail":"document locked","status":409,"title":"Conflict"}`},
+		{"invalid message", func(c *wsClient) { c.send(wsOpText, `{"text":"x"}`) }, 4422, `{"detail":"validation failed: op: is required","status":422,"title":"Unprocessable Entity"}`},
+		{"malformed message", func(c *wsClient) { c.send(wsOpBinary, `{`) }, 4400, ""},
+		{"unmasked frame", func(c *wsClient) { c.writeFrame(true, wsOpText, []byte(`{}`), false) }, WSCloseProtocolError, "unmasked client frame"},
+		{"invalid UTF-8", func(c *wsClient) { c.send(wsOpText, "\xff") }, WSCloseInvalidPayload, "invalid UTF-8"},
+		{"message too big", func(c *wsClient) { c.send(wsOpText, strings.Repeat("x", 65)) }, WSCloseMessageTooBig, "message too big"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := dialWS(t, srv, "/docs/1/edit", nil)
+			tc.send(c)
+			code, reason := c.readClose()
+			if code != tc.code || (tc.reason != "" && reason != tc.reason) {
+				t.Fatalf("expected close %d %q, got %d %q", tc.code, tc.reason, code, reason)
+			}
+		})
+	}
+}
+
+func TestWS_Handshake(t *testing.T) {
+	srv := newWSServer(t, editHandler)
+
+	res, err := http.Get(srv.URL + "/docs/1/edit")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUpgradeRequired || res.Header.Get("Content-Type") != ProblemContentType {
+		t.Fatalf("expected 426 for a plain GET, got %d", res.StatusCode)
+	}
+	res, err = http.Head(srv.URL + "/docs/1/edit")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for HEAD, got %d", res.StatusCode)
+	}
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	if _, res := dialWS(t, srv, "/docs/1/edit", http.Header{"Origin": {"https://evil.example"}}); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a foreign origin, got %d", res.StatusCode)
+	}
+	if _, res := dialWS(t, srv, "/docs/1/edit", http.Header{"Origin": {"http://" + host}}); res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected the same origin to be allowed, got %d", res.StatusCode)
+	}
+
+	allowed := newWSServer(t, editHandler, WithAllowedOrigins("*.example.com"))
+	if _, res := dialWS(t, allowed, "/docs/1/edit", http.Header{"Origin": {"https://app.example.com"}}); res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected an allowed origin to upgrade, got %d", res.StatusCode)
+	}
+}
+
+func TestWS_HeartbeatAndDisconnect(t *testing.T) {
+	done := make(chan error, 1)
+	srv := newWSServer(t, func(ctx context.Context, conn *WSConn[editIn, editOut]) error {
+		<-ctx.Done()
+		done <- context.Cause(ctx)
+		return nil
+	}, WithHeartbeat(20*time.Millisecond))
+
+	c, _ := dialWS(t, srv, "/docs/1/edit", nil)
+	if op, _ := c.read(); op != wsOpPing {
+		t.Fatalf("expected a ping, got opcode %d", op)
+	}
+	// Never answer: the next tick finds the connection dead.
+	select {
+	case err := <-done:
+		if !errors.Is(err, errWSPingTimeout) {
+			t.Fatalf("expected a ping timeout, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not failed after an unanswered ping")
+	}
+}
+
+func TestWS_HeartbeatWhileSending(t *testing.T) {
+	chunk := strings.Repeat("x", 60<<10)
+	srv := newWSServer(t, func(ctx context.Context, conn *WSConn[editIn, editOut]) error {
+		for {
+			if err := conn.Send(ctx, editOut{Text: chunk}); err != nil {
+				return err
+			}
+		}
+	}, WithHeartbeat(20*time.Millisecond))
+
+	c, _ := dialWS(t, srv, "/docs/1/edit", nil)
+	messages, pings := 0, 0
+	for messages < 1000 || pings < 3 {
+		switch op, payload := c.read(); op {
+		case wsOpPing:
+			pings++
+			c.send(wsOpPong, payload)
+		case wsOpText:
+			messages++
+		default:
+			t.Fatalf("connection closed after %d messages and %d pings: opcode %d %q", messages, pings, op, payload)
+		}
+	}
+}
+
+func TestRouterGenTS_WebSocket(t *testing.T) {
+	r := New()
+	RegisterWS(r.EndpointGroup, WS(editHandler, "/docs/:id/edit"))
+
+	desc := r.Describe()
+	if len(desc) != 1 || desc[0].Stream != StreamWebSocket || desc[0].Req.Name != "editIn" || desc[0].Res.Name != "editOut" {
+		t.Fatalf("unexpected description %+v", desc)
+	}
+
+	var buf strings.Builder
+	if err := r.GenTS(&buf, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTS error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"webSocket?: typeof WebSocket",
+		"get_docs_id_edit(\n    params: {id: string | number },\n  ): Socket<editIn, editOut> {",
+		`return this.socket<editIn, editOut>("/docs/:id/edit", params)`,
+		"export class Socket<TSend, TReceive>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in generated client:\n%s", want, out)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := r.GenTSDir(dir, TSGenOptions{}); err != nil {
+		t.Fatalf("GenTSDir error: %v", err)
+	}
+
+	binary := New()
+	RegisterWS(binary.EndpointGroup, WSEndpoint[editIn, editOut]{Handler: editHandler, Path: "/docs/:id/edit", Codec: CBORCodec[editIn, editOut]{}})
+	err := binary.GenTS(io.Discard, TSGenOptions{})
+	if err == nil || !strings.Contains(err.Error(), "websocket /docs/:id/edit: generated clients only support JSON messages, got application/cbor in and application/cbor out") {
+		t.Fatalf("expected an error for a binary WebSocket codec, got %v", err)
+	}
+	if err := binary.GenTSDir(t.TempDir(), TSGenOptions{}); err == nil {
+		t.Fatal("expected GenTSDir to reject a binary WebSocket codec")
+	}
+
+	data, err := os.ReadFile(filepath.Clean(filepath.Join(dir, "docs.ts")))
+	if err != nil {
+		t.Fatalf("read module: %v", err)
+	}
+	module := string(data)
+	for _, want := range []string{
+		"import type { ClientOptions, Socket } from './base'\nimport { socket } from './base'\n",
+		`return socket<editIn, editOut>(this.opts, "/docs/:id/edit", params)`,
+	} {
+		if !strings.Contains(module, want) {
+			t.Fatalf("expected %q in module:\n%s", want, module)
+		}
+	}
+}